@@ -24,7 +24,7 @@ func main() {
 
 	var pattern string
 
-	var opts []grep.Opt
+	var opts []grep.Option
 	if regexp != "" {
 		opts = append(opts, grep.WithRegexps(regexp))
 	} else {
@@ -34,6 +34,6 @@ func main() {
 		opts = append(opts, grep.WithInvertMatch())
 	}
 
-	output := grep.New(pattern, opts...).Exec(os.Stdin)
+	output := grep.New(pattern, opts...).Read(os.Stdin)
 	io.Copy(os.Stdout, output)
 }
@@ -1,25 +1,15 @@
 package main
 
 import (
-	"flag"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
+	"github.com/kevin-cantwell/usrbin/pkg/grep"
 	getopt "github.com/pborman/getopt/v2"
 )
 
-var (
-// flags = []flag{
-// 	{"regexp", "e", []string{}, "use PATTERN for matching"},
-// 	{"file", "f", []string{}, "obtain PATTERN from FILE"},
-// 	{"ignore-case", "i", false, "ignore case distinctions"},
-// 	{"invert-match", "v", false, "select non-matching lines"},
-// 	{"word-regexp", "w", false, "force PATTERN to match only whole words"},
-// 	{"line-regexp", "x", false, "force PATTERN to match only whole lines"},
-// }
-)
-
 type stringSlice []string
 
 func (flag *stringSlice) String() string {
@@ -31,149 +21,119 @@ func (flag *stringSlice) Set(value string) error {
 	return nil
 }
 
-var (
-	e = getopt.ListLong("regexp", 'e', "use PATTERN for matching", "PATTERN")
-	f = getopt.ListLong("file", 'f', "obtain PATTERN from FILE", "FILE")
-	i = getopt.BoolLong("ignore-case", 'i', "ignore case distinctions")
-	v = getopt.BoolLong("invert-match", 'v', "select non-matching lines")
-	w = getopt.BoolLong("word-regexp", 'w', "force PATTERN to match only whole words")
-	x = getopt.BoolLong("line-regexp", 'x', "force PATTERN to match only whole lines")
-)
-
 func main() {
-	err := getopt.Getopt(func(opt getopt.Option) bool {
-		switch opt.ShortName() {
-		case "e":
-		case "f":
-		case "":
+	os.Exit(run(os.Args, os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs gogrep with args, which start with the program name as os.Args
+// does, and returns its exit status: 0 if a line is selected, 1 if none is and
+// 2 if an error occurs.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	set := getopt.New()
+	var (
+		e     = set.ListLong("regexp", 'e', "use PATTERN for matching", "PATTERN")
+		f     = set.ListLong("file", 'f', "obtain PATTERN from FILE", "FILE")
+		i     = set.BoolLong("ignore-case", 'i', "ignore case distinctions")
+		v     = set.BoolLong("invert-match", 'v', "select non-matching lines")
+		w     = set.BoolLong("word-regexp", 'w', "force PATTERN to match only whole words")
+		x     = set.BoolLong("line-regexp", 'x', "force PATTERN to match only whole lines")
+		H     = set.BoolLong("with-filename", 'H', "print file name with output lines")
+		label = set.StringLong("label", 0, "", "use LABEL as the standard input file name prefix", "LABEL")
+		help  = set.BoolLong("help", 0, "display this help text and exit")
+	)
+	if err := set.Getopt(args, nil); err != nil {
+		fmt.Fprintf(stderr, "gogrep: %s\n%s", err, usage)
+		return 2
+	}
+	if *help {
+		fmt.Fprint(stdout, helpText)
+		return 0
+	}
+
+	// the first operand is the pattern unless -e or -f gives the patterns
+	files := set.Args()
+	var pattern string
+	if len(*e) == 0 && len(*f) == 0 {
+		if len(files) == 0 {
+			fmt.Fprint(stderr, usage)
+			return 2
+		}
+		pattern, files = files[0], files[1:]
+	}
+
+	var opts []grep.Option
+	for _, expr := range *e {
+		opts = append(opts, grep.WithRegexps(expr))
+	}
+	for _, filename := range *f {
+		file, err := os.Open(filename)
+		if err != nil {
+			fmt.Fprintf(stderr, "gogrep: %s\n", err)
+			return 2
+		}
+		defer file.Close()
+		opts = append(opts, grep.WithFiles(file))
+	}
+	if *i {
+		opts = append(opts, grep.WithIgnoreCase())
+	}
+	if *v {
+		opts = append(opts, grep.WithInvertMatch())
+	}
+	if *w {
+		opts = append(opts, grep.WithWordRegexp())
+	}
+	if *x {
+		opts = append(opts, grep.WithLineRegexp())
+	}
+	if set.IsSet("label") {
+		opts = append(opts, grep.WithLabel(*label))
+	}
+
+	if len(files) > 1 || *H {
+		opts = append(opts, grep.WithFilename())
+	}
+
+	// standard input has no name, so it is printed as the label
+	var (
+		exitCode int
+		inputs   []grep.NamedReader
+	)
+	for _, filename := range files {
+		if filename == "-" {
+			inputs = append(inputs, grep.NamedReader{Reader: stdin})
+			continue
 		}
-		fmt.Println("Getopt:", opt.Name(), opt.Value())
-		return true
-	})
-	if err != nil {
-		// code to handle error
-		fmt.Fprintf(os.Stderr, "%T: %s\n", err, err)
+		file, err := os.Open(filename)
+		if err != nil {
+			fmt.Fprintf(stderr, "gogrep: %s: No such file or directory\n", filename)
+			exitCode = 2
+			continue
+		}
+		defer file.Close()
+		inputs = append(inputs, grep.NamedReader{Name: filename, Reader: file})
+	}
+	if len(files) == 0 {
+		inputs = append(inputs, grep.NamedReader{Reader: stdin})
+	}
+
+	// the Result of the search is its exit status, unless a file could not
+	// be opened
+	output, result := grep.New(pattern, opts...).ReadNamedWithResult(inputs...)
+	if _, err := io.Copy(stdout, output); err != nil {
+		fmt.Fprintln(stderr, err)
+	}
+	if r := int(result()); r > exitCode {
+		exitCode = r
 	}
-	fmt.Println(flag.Args())
-
-	// if len(os.Args) == 1 {
-	// 	return cmd.Usage()
-	// }
-
-	// flagset := cmd.Flags()
-
-	// var (
-	// 	pattern string
-	// 	files   = args
-	// )
-
-	// if len(args) > 0 {
-	// 	pattern = args[0]
-	// 	files = args[1:]
-	// }
-
-	// var opts []grep.Opt
-
-	// for _, flag := range flags {
-	// 	if !flagset.Lookup(flag.name).Changed {
-	// 		continue
-	// 	}
-
-	// 	switch name := flag.name; name {
-	// 	case "regexp":
-	// 		e, err := flagset.GetStringArray(name)
-	// 		if err != nil {
-	// 			return err
-	// 		}
-	// 		for _, pattern := range e {
-	// 			opts = append(opts, grep.WithRegexps(pattern))
-	// 		}
-	// 		pattern = ""
-	// 		files = args
-	// 	case "file":
-	// 		f, err := flagset.GetStringArray(name)
-	// 		if err != nil {
-	// 			return err
-	// 		}
-	// 		for _, filename := range f {
-	// 			file, err := os.Open(filename)
-	// 			if err != nil {
-	// 				return err
-	// 			}
-	// 			opts = append(opts, grep.WithFiles(file))
-	// 		}
-	// 		pattern = ""
-	// 		files = args
-	// 	case "ignore-case":
-	// 		i, err := flagset.GetBool(name)
-	// 		if err != nil {
-	// 			return err
-	// 		}
-	// 		if i {
-	// 			opts = append(opts, grep.WithIgnoreCase())
-	// 		}
-
-	// 		// TODO: if pattern == "" show usage
-	// 	case "invert-match":
-	// 		v, err := flagset.GetBool(name)
-	// 		if err != nil {
-	// 			return err
-	// 		}
-	// 		if v {
-	// 			opts = append(opts, grep.WithInvertMatch())
-	// 		}
-	// 	case "word-regexp":
-	// 		w, err := flagset.GetBool(name)
-	// 		if err != nil {
-	// 			return err
-	// 		}
-	// 		if w {
-	// 			opts = append(opts, grep.WithWordRegexp())
-	// 		}
-	// 	case "line-regexp":
-	// 		x, err := flagset.GetBool(name)
-	// 		if err != nil {
-	// 			return err
-	// 		}
-	// 		if x {
-	// 			opts = append(opts, grep.WithLineRegexp())
-	// 		}
-	// 	}
-	// }
-
-	// var inputs []io.Reader
-	// for _, filename := range files {
-	// 	if filename == "-" {
-	// 		inputs = append(inputs, os.Stdin)
-	// 		continue
-	// 	}
-	// 	file, err := os.Open(filename)
-	// 	if err != nil {
-	// 		fmt.Printf("gogrep: %s: No such file or directory\n", filename)
-	// 		exitCode = 1
-	// 		continue
-	// 	}
-	// 	inputs = append(inputs, file)
-	// 	defer file.Close()
-	// }
-
-	// var input io.Reader
-	// if len(inputs) > 0 {
-	// 	input = io.MultiReader(inputs...)
-	// } else {
-	// 	input = os.Stdin
-	// }
-
-	// output := grep.New(pattern, opts...).Exec(input)
-	// _, err := io.Copy(os.Stdout, output)
-	// return err
+	return exitCode
 }
 
 const usage = `Usage: gogrep [OPTION]... PATTERN [FILE]...
 Try 'gogrep --help' for more information.
 `
 
-const help = `Usage: gogrep [OPTION]... PATTERN [FILE]...
+const helpText = `Usage: gogrep [OPTION]... PATTERN [FILE]...
 Search for PATTERN in each FILE.
 Example: gogrep -i 'hello world' menu.h main.c
 
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(file, []byte("foo\nbar\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		args   []string
+		stdin  string
+		out    string
+		status int
+	}{
+		{name: "match", args: []string{"foo"}, stdin: "foo\nbar\n", out: "foo\n", status: 0},
+		{name: "no-match", args: []string{"baz"}, stdin: "foo\nbar\n", status: 1},
+		{name: "file", args: []string{"-i", "BAR", file}, out: "bar\n", status: 0},
+		{name: "regexp", args: []string{"-e", "foo", "-e", "bar", file}, out: "foo\nbar\n", status: 0},
+		{name: "invert-match", args: []string{"-v", "foo", "-"}, stdin: "foo\nbar\n", out: "bar\n", status: 0},
+		{name: "missing-file", args: []string{"foo", filepath.Join(dir, "missing")}, status: 2},
+		{name: "missing-file-and-match", args: []string{"foo", filepath.Join(dir, "missing"), file}, out: file + ":foo\n", status: 2},
+		{name: "label", args: []string{"--label=in", "-H", "foo"}, stdin: "foo\n", out: "in:foo\n", status: 0},
+		{name: "label/among-files", args: []string{"--label", "in", "foo", file, "-"}, stdin: "foo\n", out: file + ":foo\nin:foo\n", status: 0},
+		{name: "label/unnamed", args: []string{"-H", "foo"}, stdin: "foo\n", out: "(standard input):foo\n", status: 0},
+		{name: "bad-pattern", args: []string{"["}, stdin: "foo\n", status: 2},
+		{name: "no-pattern", status: 2},
+		{name: "invalid-option", args: []string{"--nope", "foo"}, status: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			status := run(append([]string{"gogrep"}, tt.args...), strings.NewReader(tt.stdin), &stdout, &stderr)
+			if status != tt.status {
+				t.Errorf("got status %d want %d (stderr %q)", status, tt.status, stderr.String())
+			}
+			if stdout.String() != tt.out {
+				t.Errorf("got %q want %q", stdout.String(), tt.out)
+			}
+		})
+	}
+}
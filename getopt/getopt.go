@@ -78,7 +78,7 @@ func WithLongOpts(longopts ...string) Opt {
 
 func WithSilentErrors() Opt {
 	return func(opts *Opts) {
-		opgs.silentErrors = true
+		opts.silentErrors = true
 	}
 }
 
@@ -168,52 +168,105 @@ func runeSet(s string) map[rune]bool {
 	return m
 }
 
+// Parse parses parameters against the configured options. Options are
+// reported in Output.Options in the order they appear and operands are
+// collected into Output.Args. A "--" parameter ends option parsing and
+// everything after it is an operand, even if it looks like an option. A lone
+// "-" is an operand too, since it conventionally names standard input.
+//
+// Short options may be clustered, as in -abc. An option that requires an
+// argument takes the rest of its cluster, as in -bfoo, or the next parameter
+// if nothing is left; an option with an optional argument only takes the rest
+// of its cluster, and its Value is empty without it.
+//
+// Unrecognized options are reported through Output.Err, which is also
+// returned as the error, but parsing continues past them as getopt(1) does.
 func (cmd *Getopt) Parse(parameters ...string) (*Output, error) {
 	var getoptErrs []string
 
-	var shortopts string
+	shorts := parseShortOpts(cmd.opts.shortopts)
+
+	var output Output
+
+	for i := 0; i < len(parameters); i++ {
+		p := parameters[i]
+		switch {
+		case p == "--":
+			output.Args = append(output.Args, parameters[i+1:]...)
+			return cmd.output(&output, getoptErrs)
+		case len(p) < 2 || p[0] != '-':
+			// '+' stops at the first operand, like POSIXLY_CORRECT
+			if cmd.opts.scanMode == '+' {
+				output.Args = append(output.Args, parameters[i:]...)
+				return cmd.output(&output, getoptErrs)
+			}
+			output.Args = append(output.Args, p)
+		default:
+			// a cluster of short options, e.g. -abc
+			cluster := []rune(p[1:])
+			for j := 0; j < len(cluster); j++ {
+				short, ok := shorts[cluster[j]]
+				if !ok {
+					getoptErrs = append(getoptErrs, "invalid option -- '"+string(cluster[j])+"'")
+					continue
+				}
+				option := Option{Name: "-" + short.name}
+				if short.argument {
+					// an argument takes the rest of the cluster or, if it is
+					// required and nothing is left, the next parameter
+					rest := string(cluster[j+1:])
+					j = len(cluster)
+					switch {
+					case rest != "" || short.optional:
+						option.Value = rest
+					case i+1 < len(parameters):
+						i++
+						option.Value = parameters[i]
+					default:
+						getoptErrs = append(getoptErrs, "option requires an argument -- '"+short.name+"'")
+						continue
+					}
+				}
+				output.Options = append(output.Options, option)
+			}
+		}
+	}
 
-	var s []opt
-	var prev rune
-	for i, curr := range shortopts {
-		switch curr {
-		case ':':
+	return cmd.output(&output, getoptErrs)
+}
 
-			continue
-		}
-		if shortopts[i] != ':' {
-			s[len(s)-1].argument = true
+// parseShortOpts returns the options in shortopts by their characters. A
+// character followed by ':' requires an argument, and one followed by '::'
+// takes an optional argument.
+func parseShortOpts(shortopts string) map[rune]opt {
+	shorts := map[rune]opt{}
+	chars := []rune(shortopts)
+	for i := 0; i < len(chars); i++ {
+		c := chars[i]
+		short := opt{name: string(c)}
+		if i+1 < len(chars) && chars[i+1] == ':' {
+			short.argument = true
+			i++
+			if i+1 < len(chars) && chars[i+1] == ':' {
+				short.optional = true
+				i++
+			}
 		}
-
-		prev = curr
+		shorts[c] = short
 	}
+	return shorts
+}
 
-	// var output Output
-
-	// for i := 0; i < len(parameters); i++ {
-	// 	p := parameters[i]
-	// 	switch {
-	// 	case p == "--":
-	// 		output.Args = append(output.Args, parameters[i:]...)
-	// 		return &output, nil
-	// 	case len(p) < 2:
-	// 		output.Args = append(output.Args, p)
-	// 	case p[:2] == "--":
-	// 		name := p[2:]
-	// 		opt, ok := cmd.opts.longs[name]
-	// 		if !ok {
-	// 			return nil, errors.New("getopt: unrecognized option '" + p + "'")
-	// 		}
-	// 		if opt.argument {
-	// 			if !opt.optional {
-	// 				dr
-	// 			}
-	// 		}
-	// 	case p[0] == '-':
-	// 	default:
-	// 		output.Args = append(output.Args, p)
-	// 	}
-	// }
-
-	return &output, nil
+// output attaches any parsing errors to output.
+func (cmd *Getopt) output(output *Output, getoptErrs []string) (*Output, error) {
+	if len(getoptErrs) == 0 {
+		return output, nil
+	}
+	output.Err = &Error{ReturnCode: UnparsableCode}
+	if !cmd.opts.silentErrors {
+		for _, msg := range getoptErrs {
+			output.Err.Msgs = append(output.Err.Msgs, cmd.opts.name+": "+msg)
+		}
+	}
+	return output, output.Err
 }
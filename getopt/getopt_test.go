@@ -1,6 +1,7 @@
 package getopt_test
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/kevin-cantwell/usrbin/getopt"
@@ -9,42 +10,133 @@ import (
 func TestGetopt(t *testing.T) {
 	tests := []struct {
 		name    string
-		inOpts    []getopt.Opt
+		inOpts  []getopt.Opt
 		in      []string
 		outOpts []getopt.Option
 		outArgs []string
-		outErr  *getopt.Error
 	}{
 		{
-			name: "a",
-			in:   "foo bar baz",
-			outOpts:  "",
+			name:    "a",
+			inOpts:  []getopt.Opt{getopt.WithShortOpts("abc")},
+			in:      []string{"foo", "bar", "baz"},
+			outArgs: []string{"foo", "bar", "baz"},
 		},
 		{
-			name: "b",
-			in:   "-abc",
-			out:  "-a -b -c",
+			name:    "b",
+			inOpts:  []getopt.Opt{getopt.WithShortOpts("abc")},
+			in:      []string{"-abc"},
+			outOpts: []getopt.Option{{Name: "-a"}, {Name: "-b"}, {Name: "-c"}},
+		},
+		{
+			name:    "permute",
+			inOpts:  []getopt.Opt{getopt.WithShortOpts("abc")},
+			in:      []string{"foo", "-a", "bar"},
+			outOpts: []getopt.Option{{Name: "-a"}},
+			outArgs: []string{"foo", "bar"},
+		},
+		{
+			name:    "posixly-correct",
+			inOpts:  []getopt.Opt{getopt.WithShortOpts("+abc")},
+			in:      []string{"-a", "foo", "-b"},
+			outOpts: []getopt.Option{{Name: "-a"}},
+			outArgs: []string{"foo", "-b"},
+		},
+		{
+			name:    "double-dash",
+			inOpts:  []getopt.Opt{getopt.WithShortOpts("ab")},
+			in:      []string{"-a", "--", "-b", "foo"},
+			outOpts: []getopt.Option{{Name: "-a"}},
+			outArgs: []string{"-b", "foo"},
+		},
+		{
+			name:    "double-dash/twice",
+			inOpts:  []getopt.Opt{getopt.WithShortOpts("ab")},
+			in:      []string{"--", "--", "-a"},
+			outArgs: []string{"--", "-a"},
+		},
+		{
+			name:    "dash",
+			inOpts:  []getopt.Opt{getopt.WithShortOpts("ab")},
+			in:      []string{"-a", "-", "-b"},
+			outOpts: []getopt.Option{{Name: "-a"}, {Name: "-b"}},
+			outArgs: []string{"-"},
+		},
+		{
+			name:    "required-argument",
+			inOpts:  []getopt.Opt{getopt.WithShortOpts("ab:")},
+			in:      []string{"-b", "foo", "bar"},
+			outOpts: []getopt.Option{{Name: "-b", Value: "foo"}},
+			outArgs: []string{"bar"},
+		},
+		{
+			name:    "required-argument/attached",
+			inOpts:  []getopt.Opt{getopt.WithShortOpts("ab:")},
+			in:      []string{"-abfoo", "bar"},
+			outOpts: []getopt.Option{{Name: "-a"}, {Name: "-b", Value: "foo"}},
+			outArgs: []string{"bar"},
+		},
+		{
+			name:    "required-argument/looks-like-option",
+			inOpts:  []getopt.Opt{getopt.WithShortOpts("ab:")},
+			in:      []string{"-b", "-a", "--"},
+			outOpts: []getopt.Option{{Name: "-b", Value: "-a"}},
+		},
+		{
+			name:    "optional-argument",
+			inOpts:  []getopt.Opt{getopt.WithShortOpts("ac::")},
+			in:      []string{"-cfoo", "-c", "bar"},
+			outOpts: []getopt.Option{{Name: "-c", Value: "foo"}, {Name: "-c"}},
+			outArgs: []string{"bar"},
+		},
+		{
+			name:    "optional-argument/cluster",
+			inOpts:  []getopt.Opt{getopt.WithShortOpts("ac::")},
+			in:      []string{"-aca"},
+			outOpts: []getopt.Option{{Name: "-a"}, {Name: "-c", Value: "a"}},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			output, err := getopt.New(
-				"-a","--aye",
-				"b",
-				"abc", "aye,bee,cee", tt.inOpts
-				).Parse("foo", "bar", "baz")
+			output, err := getopt.New(tt.inOpts...).Parse(tt.in...)
 			if err != nil {
 				t.Fatalf("got err: %+v", err)
 			}
-
-			for _, opt := range output.Options {
-
+			if !reflect.DeepEqual(output.Options, tt.outOpts) {
+				t.Errorf("got options %+v want %+v", output.Options, tt.outOpts)
 			}
-			if string(b) != tt.out {
-				t.Errorf("got %q want %q", b, tt.out)
+			if !reflect.DeepEqual(output.Args, tt.outArgs) {
+				t.Errorf("got args %q want %q", output.Args, tt.outArgs)
 			}
 		})
 	}
+}
+
+func TestGetopt_invalidOption(t *testing.T) {
+	output, err := getopt.New(getopt.WithShortOpts("a")).Parse("-ax", "foo")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if output.Err.ReturnCode != getopt.UnparsableCode {
+		t.Errorf("got return code %d want %d", output.Err.ReturnCode, getopt.UnparsableCode)
+	}
+	if want := "getopt: invalid option -- 'x'"; err.Error() != want {
+		t.Errorf("got %q want %q", err.Error(), want)
+	}
+	if !reflect.DeepEqual(output.Options, []getopt.Option{{Name: "-a"}}) {
+		t.Errorf("got options %+v", output.Options)
+	}
+}
 
+func TestGetopt_missingArgument(t *testing.T) {
+	output, err := getopt.New(getopt.WithShortOpts("ab:")).Parse("-a", "-b")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if want := "getopt: option requires an argument -- 'b'"; err.Error() != want {
+		t.Errorf("got %q want %q", err.Error(), want)
+	}
+	if !reflect.DeepEqual(output.Options, []getopt.Option{{Name: "-a"}}) {
+		t.Errorf("got options %+v", output.Options)
+	}
 }
@@ -1,6 +1,6 @@
 module github.com/kevin-cantwell/usrbin
 
-go 1.12
+go 1.16
 
 require (
 	github.com/pborman/getopt v0.0.0-20190409184431-ee0cd42419d3
@@ -6,6 +6,15 @@ import (
 	"github.com/kevin-cantwell/usrbin/pkg/grep"
 )
 
-func Grep(input io.Reader, pattern string, opts ...grep.Opt) io.Reader {
-	return grep.New(pattern, opts...).Exec(input)
+// A *grep.Grep runs as an Execer over the files named by its params.
+var _ Execer = (*grep.Grep)(nil)
+
+// A *grep.Highlighter colors the output of a stage before it in a Pipe.
+var _ Reader = (*grep.Highlighter)(nil)
+
+// Grep searches input for pattern with opts, which are the package's own
+// Options, and returns the matching lines, as grep.New(pattern,
+// opts...).Read(input) does.
+func Grep(input io.Reader, pattern string, opts ...grep.Option) io.Reader {
+	return grep.New(pattern, opts...).Read(input)
 }
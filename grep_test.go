@@ -0,0 +1,79 @@
+package usrbin_test
+
+import (
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/kevin-cantwell/usrbin"
+	"github.com/kevin-cantwell/usrbin/pkg/grep"
+)
+
+// TestGrepImports guards against a second grep package creeping back in:
+// every grep import in the module must be of pkg/grep.
+func TestGrepImports(t *testing.T) {
+	const canonical = "github.com/kevin-cantwell/usrbin/pkg/grep"
+
+	err := filepath.Walk(".", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && strings.HasPrefix(info.Name(), ".") && path != "." {
+			return filepath.SkipDir
+		}
+		if info.IsDir() || filepath.Ext(path) != ".go" {
+			return nil
+		}
+
+		f, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.ImportsOnly)
+		if err != nil {
+			return err
+		}
+		for _, spec := range f.Imports {
+			imp, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				return err
+			}
+			if strings.HasPrefix(imp, "github.com/kevin-cantwell/usrbin/") && filepath.Base(imp) == "grep" && imp != canonical {
+				t.Errorf("%s imports %s, want %s", path, imp, canonical)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestBuild builds every package in the module, including the examples that
+// go build ./... skips, so they keep to the canonical grep API.
+func TestBuild(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the module")
+	}
+	gocmd, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip(err)
+	}
+	out, err := exec.Command(gocmd, "build", "-o", t.TempDir(), "./...", "./_examples/...").CombinedOutput()
+	if err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+}
+
+func TestGrep(t *testing.T) {
+	in := strings.NewReader("Foo 1\nbar\nfoo 2\nBAZ\n")
+	body, err := ioutil.ReadAll(usrbin.Grep(in, "FOO", grep.WithIgnoreCase(), grep.WithInvertMatch()))
+	if err != nil {
+		t.Fatalf("got err: %#v", err)
+	}
+	if want := "bar\nBAZ\n"; string(body) != want {
+		t.Fatalf("got %q want %q", string(body), want)
+	}
+}
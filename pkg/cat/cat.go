@@ -0,0 +1,180 @@
+/*
+	Package cat exposes cat-like functionality. A best effort is made to
+	mirror GNU coreutils 8.30 cat (https://www.gnu.org/software/coreutils/manual/html_node/cat-invocation.html).
+*/
+package cat
+
+import (
+	"io"
+	"os"
+)
+
+// Option configures a Cat.
+type Option func(*Opts)
+
+// WithShowAll is equivalent to WithShowNonprinting, WithShowEnds and
+// WithShowTabs together.
+func WithShowAll() Option {
+	return func(opts *Opts) {
+		opts.v = true
+		opts.E = true
+		opts.T = true
+	}
+}
+
+// WithShowEnds displays a ‘$’ at the end of each line.
+func WithShowEnds() Option {
+	return func(opts *Opts) {
+		opts.E = true
+	}
+}
+
+// WithShowTabs displays TAB characters as ‘^I’.
+func WithShowTabs() Option {
+	return func(opts *Opts) {
+		opts.T = true
+	}
+}
+
+// WithShowNonprinting displays control characters except for LFD and TAB
+// using ‘^’ notation and precedes characters that have the high bit set with
+// ‘M-’.
+func WithShowNonprinting() Option {
+	return func(opts *Opts) {
+		opts.v = true
+	}
+}
+
+type Opts struct {
+	//   -A, --show-all           equivalent to -vET
+	//   -b, --number-nonblank    number nonempty output lines, overrides -n
+	//   -e                       equivalent to -vE
+	//   -E, --show-ends          display $ at end of each line
+	E bool
+	//   -n, --number             number all output lines
+	//   -s, --squeeze-blank      suppress repeated empty output lines
+	//   -t                       equivalent to -vT
+	//   -T, --show-tabs          display TAB characters as ^I
+	T bool
+	//   -u                       (ignored)
+	//   -v, --show-nonprinting   use ^ and M- notation, except for LFD and TAB
+	v bool
+}
+
+// Cat copies its input to its output, optionally transforming it for display.
+type Cat struct {
+	opts *Opts
+}
+
+// New returns a Cat with opts set.
+func New(opts ...Option) *Cat {
+	Opts := &Opts{}
+	for _, opt := range opts {
+		opt(Opts)
+	}
+	return &Cat{
+		opts: Opts,
+	}
+}
+
+// Exec concatenates the files named by params, reading standard input for
+// "-" or when params is empty. If a file cannot be read, the output ends with
+// that error once the files before it have been copied.
+func (cmd *Cat) Exec(params []string) io.Reader {
+	if len(params) == 0 {
+		params = []string{"-"}
+	}
+
+	r, w := io.Pipe()
+
+	go func() {
+		for _, name := range params {
+			if err := copyFile(w, name); err != nil {
+				w.CloseWithError(err)
+				return
+			}
+		}
+		w.Close()
+	}()
+
+	return cmd.Read(r)
+}
+
+// copyFile copies the named file, or standard input for "-", to w.
+func copyFile(w io.Writer, name string) error {
+	if name == "-" {
+		_, err := io.Copy(w, os.Stdin)
+		return err
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+func (cmd *Cat) Read(input io.Reader) io.Reader {
+	if !cmd.opts.v && !cmd.opts.E && !cmd.opts.T {
+		return input
+	}
+
+	r, w := io.Pipe()
+
+	go func() {
+		buf := make([]byte, 32*1024)
+		var out []byte
+		for {
+			n, err := input.Read(buf)
+			out = out[:0]
+			for _, c := range buf[:n] {
+				out = cmd.appendByte(out, c)
+			}
+			if _, werr := w.Write(out); werr != nil {
+				w.CloseWithError(werr)
+				return
+			}
+			if err == io.EOF {
+				w.Close()
+				return
+			}
+			if err != nil {
+				w.CloseWithError(err)
+				return
+			}
+		}
+	}()
+
+	return r
+}
+
+// appendByte appends the display form of c to out.
+func (cmd *Cat) appendByte(out []byte, c byte) []byte {
+	switch {
+	case c == '\n':
+		if cmd.opts.E {
+			out = append(out, '$')
+		}
+		return append(out, c)
+	case c == '\t':
+		if cmd.opts.T {
+			return append(out, '^', 'I')
+		}
+		return append(out, c)
+	case !cmd.opts.v:
+		return append(out, c)
+	}
+
+	if c >= 128 {
+		out = append(out, 'M', '-')
+		c -= 128
+	}
+	switch {
+	case c < 32:
+		return append(out, '^', c+64)
+	case c == 127:
+		return append(out, '^', '?')
+	}
+	return append(out, c)
+}
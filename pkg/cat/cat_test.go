@@ -0,0 +1,130 @@
+package cat_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kevin-cantwell/usrbin/pkg/cat"
+)
+
+func TestCat(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []cat.Option
+		in   string
+		out  string
+	}{
+		{
+			name: "plain",
+			in:   "foo\x01\tbar\n",
+			out:  "foo\x01\tbar\n",
+		},
+		{
+			name: "WithShowNonprinting/control",
+			opts: []cat.Option{cat.WithShowNonprinting()},
+			in:   "a\x00b\x01c\x1bd\x7f\n",
+			out:  "a^@b^Ac^[d^?\n",
+		},
+		{
+			name: "WithShowNonprinting/high",
+			opts: []cat.Option{cat.WithShowNonprinting()},
+			in:   "\x80\x89\xc3\xa9\xff\n",
+			out:  "M-^@M-^IM-CM-)M-^?\n",
+		},
+		{
+			name: "WithShowNonprinting/tab-newline",
+			opts: []cat.Option{cat.WithShowNonprinting()},
+			in:   "a\tb\nc\n",
+			out:  "a\tb\nc\n",
+		},
+		{
+			name: "WithShowTabs",
+			opts: []cat.Option{cat.WithShowTabs()},
+			in:   "a\tb\x01\n",
+			out:  "a^Ib\x01\n",
+		},
+		{
+			name: "WithShowEnds",
+			opts: []cat.Option{cat.WithShowEnds()},
+			in:   "a\nb\n\nc",
+			out:  "a$\nb$\n$\nc",
+		},
+		{
+			name: "WithShowAll",
+			opts: []cat.Option{cat.WithShowAll()},
+			in:   "a\tb\x02\xe2\n",
+			out:  "a^Ib^BM-b$\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := strings.NewReader(tt.in)
+
+			out := cat.New(tt.opts...).Read(in)
+
+			if body, err := ioutil.ReadAll(out); err != nil {
+				t.Fatalf("got err: %#v", err)
+			} else if string(body) != tt.out {
+				t.Fatalf("got %q want %q", string(body), tt.out)
+			}
+		})
+	}
+}
+
+func TestCat_Exec(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+	if err := ioutil.WriteFile(a, []byte("foo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(b, []byte("bar\tbaz\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		opts   []cat.Option
+		params []string
+		out    string
+		err    bool
+	}{
+		{
+			name:   "files",
+			params: []string{a, b, a},
+			out:    "foo\nbar\tbaz\nfoo\n",
+		},
+		{
+			name:   "WithShowTabs",
+			opts:   []cat.Option{cat.WithShowTabs()},
+			params: []string{b},
+			out:    "bar^Ibaz\n",
+		},
+		{
+			name:   "missing",
+			params: []string{a, filepath.Join(dir, "missing"), b},
+			out:    "foo\n",
+			err:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := cat.New(tt.opts...).Exec(tt.params)
+
+			body, err := ioutil.ReadAll(out)
+			if (err != nil) != tt.err {
+				t.Fatalf("got err: %#v", err)
+			}
+			if string(body) != tt.out {
+				t.Fatalf("got %q want %q", string(body), tt.out)
+			}
+		})
+	}
+}
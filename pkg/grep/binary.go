@@ -0,0 +1,30 @@
+package grep
+
+import (
+	"bytes"
+	"unicode"
+	"unicode/utf8"
+)
+
+// defaultBinaryLineThreshold is the share of non-printable characters above
+// which WithSkipBinaryLines treats a line as binary.
+const defaultBinaryLineThreshold = 0.3
+
+// binaryLine reports whether line contains a NUL byte or more than threshold
+// non-printable characters, counting invalid UTF-8 as non-printable.
+func binaryLine(line []byte, threshold float64) bool {
+	if bytes.IndexByte(line, 0) >= 0 {
+		return true
+	}
+
+	var total, nonprinting int
+	for len(line) > 0 {
+		r, size := utf8.DecodeRune(line)
+		line = line[size:]
+		total++
+		if r == utf8.RuneError && size == 1 || r != '\t' && unicode.IsControl(r) {
+			nonprinting++
+		}
+	}
+	return total > 0 && float64(nonprinting)/float64(total) > threshold
+}
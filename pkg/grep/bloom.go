@@ -0,0 +1,87 @@
+package grep
+
+// gramSize is the longest n-gram inserted into a bloom filter. Longer grams
+// reject more lines, but the filter can use no more than the shortest
+// literal's length.
+const gramSize = 8
+
+// bloom is a bloom filter over the leading n-gram of each literal pattern. A
+// line can only contain a literal if it contains that literal's leading
+// n-gram, so a line none of whose n-grams are in the filter cannot match.
+type bloom struct {
+	bits []uint64
+	k    int
+	n    int
+}
+
+// newBloom returns a bloom filter for literals, or nil if any literal is too
+// short to filter on.
+func newBloom(literals []string) *bloom {
+	n := gramSize
+	for _, lit := range literals {
+		if len(lit) < n {
+			n = len(lit)
+		}
+	}
+	if n == 0 || len(literals) == 0 {
+		return nil
+	}
+
+	// ~10 bits per literal with 7 hashes gives a false positive rate under 1%
+	words := (len(literals)*10)/64 + 1
+	b := &bloom{bits: make([]uint64, words), k: 7, n: n}
+	for _, lit := range literals {
+		b.add([]byte(lit[:n]))
+	}
+	return b
+}
+
+// The FNV-1a offset basis and prime, as in hash/fnv.
+const (
+	fnvOffset64 = 14695981039346656037
+	fnvPrime64  = 1099511628211
+)
+
+// hashes returns the two hashes of gram that its k bits are derived from. The
+// first is FNV-1a, computed inline since this runs for every n-gram of every
+// line.
+func (b *bloom) hashes(gram []byte) (uint64, uint64) {
+	h1 := uint64(fnvOffset64)
+	for _, c := range gram {
+		h1 ^= uint64(c)
+		h1 *= fnvPrime64
+	}
+	return h1, h1>>33 | 1
+}
+
+func (b *bloom) add(gram []byte) {
+	h1, h2 := b.hashes(gram)
+	m := uint64(len(b.bits) * 64)
+	for i := 0; i < b.k; i++ {
+		bit := (h1 + uint64(i)*h2) % m
+		b.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+func (b *bloom) has(gram []byte) bool {
+	h1, h2 := b.hashes(gram)
+	m := uint64(len(b.bits) * 64)
+	for i := 0; i < b.k; i++ {
+		bit := (h1 + uint64(i)*h2) % m
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// mayMatch reports whether line might contain one of the literals. A false
+// result is definite.
+func (b *bloom) mayMatch(line []byte) bool {
+	for i := 0; i+b.n <= len(line); i++ {
+		if b.has(line[i : i+b.n]) {
+			return true
+		}
+	}
+	return false
+}
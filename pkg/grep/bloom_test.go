@@ -0,0 +1,93 @@
+package grep_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/kevin-cantwell/usrbin/pkg/grep"
+)
+
+// blocklist returns n distinct literal patterns and an input of lines where
+// roughly one line in hits contains one of them.
+func blocklist(n, lines, hits int) (string, string) {
+	rnd := rand.New(rand.NewSource(1))
+	word := func() string {
+		b := make([]byte, 8)
+		for i := range b {
+			b[i] = byte('a' + rnd.Intn(26))
+		}
+		return string(b)
+	}
+
+	literals := make([]string, n)
+	for i := range literals {
+		literals[i] = fmt.Sprintf("%s%d", word(), i)
+	}
+
+	var in strings.Builder
+	for i := 0; i < lines; i++ {
+		if i%hits == 0 {
+			in.WriteString(literals[rnd.Intn(n)] + " ")
+		}
+		in.WriteString(word() + " " + word() + "\n")
+	}
+	return strings.Join(literals, "\n"), in.String()
+}
+
+func TestWithBloomPrefilter(t *testing.T) {
+	patterns, in := blocklist(5000, 500, 7)
+
+	for _, opts := range [][]grep.Option{
+		nil,
+		{grep.WithInvertMatch()},
+		{grep.WithWordRegexp()},
+	} {
+		want, err := ioutil.ReadAll(grep.New(patterns, opts...).Read(strings.NewReader(in)))
+		if err != nil {
+			t.Fatalf("got err: %#v", err)
+		}
+		got, err := ioutil.ReadAll(grep.New(patterns, append(opts, grep.WithBloomPrefilter())...).Read(strings.NewReader(in)))
+		if err != nil {
+			t.Fatalf("got err: %#v", err)
+		}
+		if len(want) == 0 {
+			t.Fatal("expected some output")
+		}
+		if string(got) != string(want) {
+			t.Fatalf("prefiltered output differs: got %d bytes want %d bytes", len(got), len(want))
+		}
+	}
+}
+
+func TestWithBloomPrefilter_nonLiteral(t *testing.T) {
+	out := grep.New("x+y\nfoo", grep.WithBloomPrefilter()).Read(strings.NewReader("xxy\nbar\nfoo"))
+	if body, err := ioutil.ReadAll(out); err != nil {
+		t.Fatalf("got err: %#v", err)
+	} else if string(body) != "xxy\nfoo\n" {
+		t.Fatalf("got %q want %q", string(body), "xxy\nfoo\n")
+	}
+}
+
+func BenchmarkBloomPrefilter(b *testing.B) {
+	patterns, in := blocklist(5000, 500, 100)
+
+	for _, bench := range []struct {
+		name string
+		opts []grep.Option
+	}{
+		{"regexp", nil},
+		{"bloom", []grep.Option{grep.WithBloomPrefilter()}},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				out := grep.New(patterns, bench.opts...).Read(strings.NewReader(in))
+				if _, err := ioutil.ReadAll(out); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package grep
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// translateBRE rewrites expr, a POSIX basic regular expression with the GNU
+// extensions \+, \? and \|, as the equivalent RE2 expression. In a BRE, the
+// characters + ? | ( ) { } are literal and only special when escaped, while *
+// is literal at the start of an expression, as are ^ and $ anywhere but at its
+// start and end. \< and \> become \b, which also matches at the other end of a
+// word. Backreferences have no RE2 equivalent and are rejected.
+func translateBRE(expr string) (string, error) {
+	var b strings.Builder
+	// whether the next character starts an expression, where * is literal and
+	// ^ is an anchor
+	start := true
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		atStart := start
+		start = false
+		switch c {
+		case '\\':
+			i++
+			if i == len(expr) {
+				return "", errors.New("grep: trailing backslash (\\)")
+			}
+			switch c := expr[i]; c {
+			case '(':
+				b.WriteByte('(')
+				start = true
+			case '|':
+				b.WriteByte('|')
+				start = true
+			case ')', '+', '?':
+				b.WriteByte(c)
+			case '{':
+				end := strings.Index(expr[i:], `\}`)
+				if end < 0 {
+					return "", errors.New(`grep: unmatched \{`)
+				}
+				b.WriteByte('{')
+				b.WriteString(expr[i+1 : i+end])
+				b.WriteByte('}')
+				i += end + 1
+			case '<', '>':
+				b.WriteString(`\b`)
+			case 'b', 'B', 'w', 'W', 's', 'S':
+				b.WriteByte('\\')
+				b.WriteByte(c)
+			case '`':
+				b.WriteString(`\A`)
+			case '\'':
+				b.WriteString(`\z`)
+			case '1', '2', '3', '4', '5', '6', '7', '8', '9':
+				return "", fmt.Errorf("grep: backreference \\%c is not supported", c)
+			default:
+				b.WriteString(quoteByte(c))
+			}
+		case '+', '?', '|', '(', ')', '{', '}':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '*':
+			if atStart {
+				b.WriteString(`\*`)
+			} else {
+				b.WriteByte('*')
+			}
+		case '^':
+			if atStart {
+				b.WriteByte('^')
+				start = true
+			} else {
+				b.WriteString(`\^`)
+			}
+		case '$':
+			if rest := expr[i+1:]; rest == "" || strings.HasPrefix(rest, `\)`) || strings.HasPrefix(rest, `\|`) {
+				b.WriteByte('$')
+			} else {
+				b.WriteString(`\$`)
+			}
+		case '[':
+			end, err := translateBracket(&b, expr[i:])
+			if err != nil {
+				return "", err
+			}
+			i += end - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
+
+// translateBracket writes the bracket expression at the start of expr as RE2
+// and returns its length. Backslashes are literal within it, and [ only
+// starts a character class such as [:alpha:].
+func translateBracket(b *strings.Builder, expr string) (int, error) {
+	b.WriteByte('[')
+	i := 1
+	if i < len(expr) && expr[i] == '^' {
+		b.WriteByte('^')
+		i++
+	}
+	if i < len(expr) && expr[i] == ']' {
+		// a leading ] is literal
+		b.WriteString(`\]`)
+		i++
+	}
+	for ; i < len(expr); i++ {
+		switch c := expr[i]; c {
+		case ']':
+			b.WriteByte(']')
+			return i + 1, nil
+		case '[':
+			if strings.HasPrefix(expr[i:], "[:") {
+				end := strings.Index(expr[i+2:], ":]")
+				if end < 0 {
+					return 0, errors.New("grep: unmatched [:")
+				}
+				b.WriteString(expr[i : i+2+end+2])
+				i += 2 + end + 1
+				continue
+			}
+			if strings.HasPrefix(expr[i:], "[=") || strings.HasPrefix(expr[i:], "[.") {
+				return 0, fmt.Errorf("grep: %s is not supported", expr[i:i+2])
+			}
+			b.WriteString(`\[`)
+		case '\\':
+			b.WriteString(`\\`)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return 0, errors.New("grep: unmatched [")
+}
+
+// quoteByte returns c escaped to match itself in RE2.
+func quoteByte(c byte) string {
+	if strings.IndexByte(`\.+*?()|[]{}^$`, c) >= 0 {
+		return `\` + string(c)
+	}
+	return string(c)
+}
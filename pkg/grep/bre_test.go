@@ -0,0 +1,150 @@
+package grep_test
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/kevin-cantwell/usrbin/pkg/grep"
+)
+
+func TestWithBasicRegexp(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		opts    []grep.Option
+		in      string
+		out     string
+		err     bool
+	}{
+		{
+			name:    "literal-specials",
+			pattern: "a+(b)?|{c}",
+			in:      "aab\na+(b)?|{c}\nab",
+			out:     "a+(b)?|{c}\n",
+		},
+		{
+			name:    "group-alternation",
+			pattern: `\(foo\|bar\)baz`,
+			in:      "foobaz\nbarbaz\nbaz\n(foo|bar)baz",
+			out:     "foobaz\nbarbaz\n",
+		},
+		{
+			name:    "interval",
+			pattern: `^a\{2,3\}$`,
+			in:      "a\naa\naaa\naaaa",
+			out:     "aa\naaa\n",
+		},
+		{
+			name:    "plus-question",
+			pattern: `^ab\+c\?$`,
+			in:      "ab\nabbbc\nac\nabcc",
+			out:     "ab\nabbbc\n",
+		},
+		{
+			name:    "leading-star",
+			pattern: `*a`,
+			in:      "*a\na\nbbba",
+			out:     "*a\n",
+		},
+		{
+			name:    "star-after-group",
+			pattern: `\(*x\)`,
+			in:      "*x\nx",
+			out:     "*x\n",
+		},
+		{
+			name:    "star",
+			pattern: `ab*c`,
+			in:      "ac\nabbbc\nadc",
+			out:     "ac\nabbbc\n",
+		},
+		{
+			name:    "anchors",
+			pattern: `^a^b$c$`,
+			in:      "a^b$c\nabc",
+			out:     "a^b$c\n",
+		},
+		{
+			name:    "anchors/in-group",
+			pattern: `\(^a\|b$\)`,
+			in:      "ax\nxb\nxa\nbx",
+			out:     "ax\nxb\n",
+		},
+		{
+			name:    "word-boundaries",
+			pattern: `\<the\>`,
+			in:      "the end\nother\nbathe",
+			out:     "the end\n",
+		},
+		{
+			name:    "bracket",
+			pattern: `[]a\]x`,
+			in:      "]x\nax\n\\x\nbx",
+			out:     "]x\nax\n\\x\n",
+		},
+		{
+			name:    "bracket/class",
+			pattern: `^[[:digit:]+]*$`,
+			in:      "12+3\n12a\n+",
+			out:     "12+3\n+\n",
+		},
+		{
+			name:    "escaped-specials",
+			pattern: `a\.b\*\[`,
+			in:      "a.b*[\naxb*[",
+			out:     "a.b*[\n",
+		},
+		{
+			name:    "WithIgnoreCase",
+			pattern: `\(ab\)\+`,
+			opts:    []grep.Option{grep.WithIgnoreCase()},
+			in:      "ABab\nba",
+			out:     "ABab\n",
+		},
+		{
+			name:    "WithExtendedRegexp-applied-last",
+			pattern: `a+`,
+			opts:    []grep.Option{grep.WithExtendedRegexp()},
+			in:      "aa\na+",
+			out:     "aa\na+\n",
+		},
+		{
+			name:    "WithFixedStrings-conflicts",
+			pattern: `a\+`,
+			opts:    []grep.Option{grep.WithFixedStrings()},
+			in:      "aa\na\\+",
+			err:     true,
+		},
+		{
+			name:    "backreference",
+			pattern: `\(a\)\1`,
+			in:      "aa",
+			err:     true,
+		},
+		{
+			name:    "trailing-backslash",
+			pattern: `a\`,
+			in:      "a",
+			err:     true,
+		},
+		{
+			name:    "unmatched-bracket",
+			pattern: `[a`,
+			in:      "a",
+			err:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]grep.Option{grep.WithBasicRegexp()}, tt.opts...)
+			out, err := ioutil.ReadAll(grep.New(tt.pattern, opts...).Read(strings.NewReader(tt.in)))
+			if (err != nil) != tt.err {
+				t.Fatalf("got err %v", err)
+			}
+			if string(out) != tt.out {
+				t.Errorf("got %q want %q", out, tt.out)
+			}
+		})
+	}
+}
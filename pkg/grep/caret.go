@@ -0,0 +1,80 @@
+package grep
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// appendCarets appends to dst a line pointing at spans of line with carets,
+// as compilers point at errors. prefix is whatever precedes line on output,
+// such as a file name. Tabs are copied so the carets line up at any tab width
+// and other characters are replaced by as many spaces as their display width.
+func appendCarets(dst, prefix, line []byte, spans [][]int) []byte {
+	dst = appendBlanks(dst, prefix)
+	var end int
+	for _, span := range spans {
+		dst = appendBlanks(dst, line[end:span[0]])
+		n := displayWidth(line[span[0]:span[1]])
+		if n == 0 {
+			n = 1
+		}
+		for i := 0; i < n; i++ {
+			dst = append(dst, '^')
+		}
+		end = span[1]
+	}
+	return dst
+}
+
+// appendBlanks appends to dst the tabs and spaces that span the same columns
+// as text.
+func appendBlanks(dst, text []byte) []byte {
+	for len(text) > 0 {
+		r, size := utf8.DecodeRune(text)
+		text = text[size:]
+		if r == '\t' {
+			dst = append(dst, '\t')
+			continue
+		}
+		for i := 0; i < runeWidth(r); i++ {
+			dst = append(dst, ' ')
+		}
+	}
+	return dst
+}
+
+// displayWidth returns the number of terminal columns text occupies, counting
+// tabs as one.
+func displayWidth(text []byte) int {
+	var n int
+	for len(text) > 0 {
+		r, size := utf8.DecodeRune(text)
+		text = text[size:]
+		n += runeWidth(r)
+	}
+	return n
+}
+
+// runeWidth approximates the number of terminal columns r occupies: none for
+// combining marks and control characters, two for East Asian wide and
+// fullwidth characters and emoji, and one otherwise.
+func runeWidth(r rune) int {
+	switch {
+	case r == '\t':
+		return 1
+	case unicode.Is(unicode.Mn, r), unicode.Is(unicode.Me, r), unicode.IsControl(r):
+		return 0
+	case r >= 0x1100 && r <= 0x115F,
+		r >= 0x2E80 && r <= 0xA4CF && r != 0x303F,
+		r >= 0xAC00 && r <= 0xD7A3,
+		r >= 0xF900 && r <= 0xFAFF,
+		r >= 0xFE30 && r <= 0xFE4F,
+		r >= 0xFF00 && r <= 0xFF60,
+		r >= 0xFFE0 && r <= 0xFFE6,
+		r >= 0x1F300 && r <= 0x1F64F,
+		r >= 0x1F900 && r <= 0x1F9FF,
+		r >= 0x20000 && r <= 0x3FFFD:
+		return 2
+	}
+	return 1
+}
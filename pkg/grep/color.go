@@ -0,0 +1,56 @@
+package grep
+
+import "bytes"
+
+// The SGR sequences GNU grep brackets matches with by default. "\x1b[K"
+// erases to the end of line so that background colors do not bleed.
+const (
+	colorMatch = "\x1b[01;31m\x1b[K"
+	colorReset = "\x1b[m\x1b[K"
+	// dims context lines; GNU grep has no equivalent
+	colorDim = "\x1b[2m\x1b[K"
+)
+
+// Markers standing in for trailing whitespace made visible.
+const (
+	visibleSpace = "·"
+	visibleTab   = "→"
+)
+
+// appendDisplayed appends line to dst as it is shown: with spans highlighted
+// and, if visible is set, its trailing spaces and tabs replaced by markers.
+func appendDisplayed(dst, line []byte, spans [][]int, visible bool) []byte {
+	trail := len(line)
+	if visible {
+		trail = len(bytes.TrimRight(line, " \t"))
+	}
+	var end int
+	for _, span := range spans {
+		dst = appendVisible(dst, line, end, span[0], trail)
+		dst = append(dst, colorMatch...)
+		dst = appendVisible(dst, line, span[0], span[1], trail)
+		dst = append(dst, colorReset...)
+		end = span[1]
+	}
+	return appendVisible(dst, line, end, len(line), trail)
+}
+
+// appendVisible appends line[begin:end] to dst, replacing the whitespace
+// from trail on with markers.
+func appendVisible(dst, line []byte, begin, end, trail int) []byte {
+	if end <= trail {
+		return append(dst, line[begin:end]...)
+	}
+	if begin < trail {
+		dst = append(dst, line[begin:trail]...)
+		begin = trail
+	}
+	for _, c := range line[begin:end] {
+		if c == '\t' {
+			dst = append(dst, visibleTab...)
+		} else {
+			dst = append(dst, visibleSpace...)
+		}
+	}
+	return dst
+}
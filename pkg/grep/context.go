@@ -0,0 +1,179 @@
+package grep
+
+import "io"
+
+// contextPrinter prints selected lines along with their context, merging
+// overlapping and adjacent context windows into blocks so that every line is
+// printed at most once. Blocks are separated by "--".
+type contextPrinter struct {
+	b, a int
+
+	// up to b unprinted lines preceding the current one; with WithSpillContext
+	// the oldest of them may be in spill instead, once the bytes held in
+	// before exceed threshold
+	before    []contextLine
+	held      int
+	spill     *spillFile
+	threshold int
+	// lines of trailing context still to print
+	after int
+	// number of the last line printed from the current input, or 0
+	last int
+	// whether any block has been printed
+	printed bool
+}
+
+// contextLine is a line held back in case it turns out to be context.
+type contextLine struct {
+	lineNo int
+	offset int64
+	line   []byte
+	eol    []byte
+}
+
+func newContextPrinter(opts *Opts) *contextPrinter {
+	if opts.listsInputs() || opts.A <= 0 && opts.B <= 0 {
+		return nil
+	}
+	c := &contextPrinter{b: opts.B, a: opts.A}
+	if opts.spillContext {
+		c.spill = newSpillFile(opts.spillDir)
+		c.threshold = opts.spillThreshold
+		if c.threshold <= 0 {
+			c.threshold = defaultSpillThreshold
+		}
+	}
+	return c
+}
+
+// spilled returns the number of lines of leading context in the spill file.
+func (c *contextPrinter) spilled() int {
+	if c.spill == nil {
+		return 0
+	}
+	return len(c.spill.lines)
+}
+
+// reset prepares for a new input, whose blocks never merge with those of the
+// previous one.
+func (c *contextPrinter) reset() error {
+	c.before = c.before[:0]
+	c.held = 0
+	c.after = 0
+	c.last = 0
+	if c.spill != nil {
+		return c.spill.truncate()
+	}
+	return nil
+}
+
+// close removes the spill file, if there is one.
+func (c *contextPrinter) close() error {
+	if c.spill == nil {
+		return nil
+	}
+	return c.spill.close()
+}
+
+// asContext prints line lineNo of input if it is trailing context, and
+// otherwise holds it back in case it is leading context.
+func (run *run) asContext(input NamedReader, lineNo int, offset int64, line []byte) error {
+	c := run.context
+	if c.after > 0 {
+		c.after--
+		c.last = lineNo
+		return run.print(input, lineNo, offset, line, '-')
+	}
+	if c.b == 0 {
+		return nil
+	}
+	switch {
+	case len(c.before)+c.spilled() < c.b:
+		c.before = append(c.before, contextLine{})
+	case c.spilled() > 0:
+		if err := c.spill.drop(); err != nil {
+			return err
+		}
+		c.before = append(c.before, contextLine{})
+	default:
+		// reuse the oldest line's storage
+		oldest := c.before[0]
+		copy(c.before, c.before[1:])
+		c.before = c.before[:c.b-1]
+		c.held -= len(oldest.line) + len(oldest.eol)
+		oldest.line = oldest.line[:0]
+		oldest.eol = oldest.eol[:0]
+		c.before = append(c.before, oldest)
+	}
+	held := &c.before[len(c.before)-1]
+	held.lineNo, held.offset = lineNo, offset
+	held.line = append(held.line, line...)
+	held.eol = append(held.eol, run.eol...)
+	c.held += len(line) + len(run.eol)
+
+	if c.spill != nil && c.held > c.threshold {
+		for _, held := range c.before {
+			if err := c.spill.add(held); err != nil {
+				return err
+			}
+		}
+		c.before = c.before[:0]
+		c.held = 0
+	}
+	return nil
+}
+
+// withContext prints line lineNo of input with its leading context, starting a
+// new block if it does not continue the last one.
+func (run *run) withContext(input NamedReader, lineNo int, offset int64, line []byte) error {
+	c := run.context
+	first := lineNo
+	switch {
+	case c.spilled() > 0:
+		first = c.spill.lines[0].lineNo
+	case len(c.before) > 0:
+		first = c.before[0].lineNo
+	}
+	if c.printed && (c.last == 0 || first > c.last+1) {
+		// GNU grep ends the separator in a newline even with WithNullData
+		if _, err := io.WriteString(run.w, "--\n"); err != nil {
+			return err
+		}
+		if err := run.lineWritten(); err != nil {
+			return err
+		}
+	}
+	eol := run.eol
+	for i := 0; i < c.spilled(); i++ {
+		l := c.spill.lines[i]
+		heldLine, heldEOL, err := c.spill.read(l)
+		if err != nil {
+			return err
+		}
+		run.eol = heldEOL
+		if err := run.print(input, l.lineNo, l.offset, heldLine, '-'); err != nil {
+			return err
+		}
+	}
+	if c.spill != nil {
+		if err := c.spill.truncate(); err != nil {
+			return err
+		}
+	}
+	for _, held := range c.before {
+		run.eol = held.eol
+		if err := run.print(input, held.lineNo, held.offset, held.line, '-'); err != nil {
+			return err
+		}
+	}
+	c.before = c.before[:0]
+	c.held = 0
+	run.eol = eol
+	if err := run.print(input, lineNo, offset, line, ':'); err != nil {
+		return err
+	}
+	c.after = c.a
+	c.last = lineNo
+	c.printed = true
+	return nil
+}
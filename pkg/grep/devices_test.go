@@ -0,0 +1,130 @@
+//go:build !windows
+// +build !windows
+
+package grep_test
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/kevin-cantwell/usrbin/pkg/grep"
+)
+
+// fifo makes a named pipe at path, writing data to it once it is opened for
+// reading.
+func fifo(t *testing.T, path, data string) {
+	t.Helper()
+	if err := syscall.Mkfifo(path, 0644); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		f, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			return
+		}
+		defer f.Close()
+		f.WriteString(data)
+	}()
+}
+
+// readAllWithin reads r to the end, failing t if that takes too long, as it
+// would if a pipe were left waiting for a writer.
+func readAllWithin(t *testing.T, r io.Reader) ([]byte, error) {
+	t.Helper()
+	type result struct {
+		body []byte
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		body, err := ioutil.ReadAll(r)
+		done <- result{body, err}
+	}()
+	select {
+	case res := <-done:
+		return res.body, res.err
+	case <-time.After(5 * time.Second):
+		t.Fatal("search did not end")
+		return nil, nil
+	}
+}
+
+func TestWithDevices(t *testing.T) {
+	dir := tree(t, map[string]string{"a.log": "ERROR 1\n"})
+	defer os.RemoveAll(dir)
+	a, pipe := filepath.Join(dir, "a.log"), filepath.Join(dir, "pipe")
+	fifo(t, pipe, "ERROR 2\n")
+	// unblock the writer left waiting by the tests that skip the pipe
+	defer func() {
+		if f, err := os.OpenFile(pipe, os.O_RDONLY|syscall.O_NONBLOCK, 0); err == nil {
+			f.Close()
+		}
+	}()
+
+	tests := []struct {
+		name string
+		opts []grep.Option
+		args []string
+		out  string
+	}{
+		{
+			name: "recursive skips",
+			opts: []grep.Option{grep.WithRecursive()},
+			args: []string{dir},
+			out:  a + ":ERROR 1\n",
+		},
+		{
+			name: "named skip",
+			opts: []grep.Option{grep.WithDevices("skip")},
+			args: []string{a, pipe},
+			out:  a + ":ERROR 1\n",
+		},
+		{
+			name: "recursive read",
+			opts: []grep.Option{grep.WithRecursive(), grep.WithDevices("read")},
+			args: []string{dir},
+			out:  a + ":ERROR 1\n" + pipe + ":ERROR 2\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := readAllWithin(t, grep.New("ERROR", tt.opts...).Exec(tt.args))
+			if err != nil {
+				t.Fatalf("got err: %#v", err)
+			}
+			if string(body) != tt.out {
+				t.Fatalf("got %q want %q", body, tt.out)
+			}
+		})
+	}
+}
+
+func TestWithDevices_named(t *testing.T) {
+	dir, err := ioutil.TempDir("", "grep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	pipe := filepath.Join(dir, "pipe")
+	fifo(t, pipe, "ERROR 1\nok\n")
+
+	body, err := readAllWithin(t, grep.New("ERROR").Exec([]string{pipe}))
+	if err != nil {
+		t.Fatalf("got err: %#v", err)
+	}
+	if want := "ERROR 1\n"; string(body) != want {
+		t.Fatalf("got %q want %q", body, want)
+	}
+}
+
+func TestWithDevices_unknown(t *testing.T) {
+	_, err := ioutil.ReadAll(grep.New("ERROR", grep.WithDevices("open")).Exec(nil))
+	if err == nil {
+		t.Fatal("got no error")
+	}
+}
@@ -0,0 +1,117 @@
+package grep
+
+import (
+	"bufio"
+	"io"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// addedLines is the set of lines each file gained in a diff, keyed by the
+// cleaned slash-separated file path.
+type addedLines map[string]map[int]bool
+
+// parseDiff reads a unified diff, such as the output of git diff or diff -u,
+// and returns the lines it adds, numbered as in the new file.
+func parseDiff(diff io.Reader) (addedLines, error) {
+	added := addedLines{}
+
+	var (
+		file   map[int]bool
+		lineNo int
+		// lines of the current hunk still to come from the old and new file
+		oldLeft, newLeft int
+	)
+	s := bufio.NewScanner(diff)
+	for s.Scan() {
+		line := s.Text()
+		if oldLeft > 0 || newLeft > 0 {
+			// a hunk line, even if it looks like a header
+			switch {
+			case strings.HasPrefix(line, "+"):
+				if file != nil {
+					file[lineNo] = true
+				}
+				lineNo++
+				newLeft--
+			case strings.HasPrefix(line, "-"):
+				oldLeft--
+			case strings.HasPrefix(line, " "), line == "":
+				lineNo++
+				oldLeft--
+				newLeft--
+			}
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "+++ "):
+			name := strings.TrimPrefix(line, "+++ ")
+			if tab := strings.IndexByte(name, '\t'); tab >= 0 {
+				name = name[:tab]
+			}
+			if name == "/dev/null" {
+				file = nil
+				continue
+			}
+			name = diffPath(strings.TrimPrefix(name, "b/"))
+			if added[name] == nil {
+				added[name] = map[int]bool{}
+			}
+			file = added[name]
+		case strings.HasPrefix(line, "@@ "):
+			// @@ -l,s +l,s @@
+			fields := strings.Fields(line)
+			if len(fields) < 3 || !strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") {
+				continue
+			}
+			_, oldN, err := hunkRange(fields[1][1:])
+			if err != nil {
+				continue
+			}
+			start, newN, err := hunkRange(fields[2][1:])
+			if err != nil {
+				continue
+			}
+			lineNo, oldLeft, newLeft = start, oldN, newN
+		}
+	}
+	return added, s.Err()
+}
+
+// hunkRange parses the l,s range of a hunk header. A missing s is 1.
+func hunkRange(r string) (start, n int, err error) {
+	parts := strings.SplitN(r, ",", 2)
+	if start, err = strconv.Atoi(parts[0]); err != nil {
+		return 0, 0, err
+	}
+	n = 1
+	if len(parts) == 2 {
+		if n, err = strconv.Atoi(parts[1]); err != nil {
+			return 0, 0, err
+		}
+	}
+	return start, n, nil
+}
+
+// inputName returns the name input is paired with files in a diff by: the
+// Name of a NamedReader, or of a file such as an *os.File.
+func inputName(input io.Reader) string {
+	switch input := input.(type) {
+	case NamedReader:
+		return input.Name
+	case interface{ Name() string }:
+		return input.Name()
+	}
+	return ""
+}
+
+// has reports whether the diff added line lineNo of the file called name.
+func (added addedLines) has(name string, lineNo int) bool {
+	return added[diffPath(name)][lineNo]
+}
+
+func diffPath(name string) string {
+	return path.Clean(filepath.ToSlash(name))
+}
@@ -0,0 +1,99 @@
+package grep_test
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/kevin-cantwell/usrbin/pkg/grep"
+)
+
+const sampleDiff = `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1,4 +1,5 @@
+ package main
+-// TODO old
++// TODO new
+ 
+ func main() {
++	panic("TODO")
+@@ -10,2 +11,3 @@ func helper() {
+ 	// TODO untouched
++	// TODO added late
+ }
+diff --git a/gone.go b/gone.go
+--- a/gone.go
++++ /dev/null
+@@ -1 +0,0 @@
+-// TODO gone
+`
+
+const sampleFile = `package main
+// TODO new
+
+func main() {
+	panic("TODO")
+}
+
+// TODO preexisting
+func helper() {
+	x := 1
+	// TODO untouched
+	// TODO added late
+}
+`
+
+func TestWithDiffFilter(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []grep.Option
+		out  string
+	}{
+		{
+			name: "added",
+			out:  "./main.go:// TODO new\n./main.go:\tpanic(\"TODO\")\n./main.go:\t// TODO added late\n",
+		},
+		{
+			name: "WithInvertMatch",
+			opts: []grep.Option{grep.WithInvertMatch()},
+			out:  "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append(tt.opts, grep.WithDiffFilter(strings.NewReader(sampleDiff)))
+			out := grep.New("TODO", opts...).ReadNamed(
+				grep.NamedReader{Name: "./main.go", Reader: strings.NewReader(sampleFile)},
+				grep.NamedReader{Name: "other.go", Reader: strings.NewReader("// TODO elsewhere\n")},
+			)
+			if body, err := ioutil.ReadAll(out); err != nil {
+				t.Fatalf("got err: %#v", err)
+			} else if string(body) != tt.out {
+				t.Fatalf("got %q want %q", string(body), tt.out)
+			}
+		})
+	}
+}
+
+func TestWithDiffFilter_addedHeaderLookalike(t *testing.T) {
+	// the added line "++ banned" is "+++ banned" in the diff, not a header
+	const diff = `--- a/list.txt
++++ b/list.txt
+@@ -1 +1,3 @@
+ ok
++++ banned
++banned2
+`
+	out := grep.New("banned", grep.WithDiffFilter(strings.NewReader(diff))).ReadNamed(
+		grep.NamedReader{Name: "list.txt", Reader: strings.NewReader("ok\n++ banned\nbanned2\n")},
+	)
+	body, err := ioutil.ReadAll(out)
+	if err != nil {
+		t.Fatalf("got err: %#v", err)
+	}
+	if want := "++ banned\nbanned2\n"; string(body) != want {
+		t.Fatalf("got %q want %q", string(body), want)
+	}
+}
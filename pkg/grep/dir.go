@@ -0,0 +1,179 @@
+package grep
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// ReadDir searches every regular file in the tree rooted at root, as grep -r
+// does, and returns the combined output. Each line is prefixed with the path
+// of its file, which starts with root, unless WithNoFilename is set. Symbolic
+// links are not followed unless WithDereferenceRecursive is set, and files
+// that look binary are skipped unless WithBinaryFiles is set.
+func (cmd *Grep) ReadDir(root string) io.Reader {
+	r, w := newPipe()
+
+	matcher, err := cmd.allMatcher()
+	if err != nil {
+		w.CloseWithError(err)
+		return r
+	}
+	types, err := newTypeFilter(cmd.opts)
+	if err != nil {
+		w.CloseWithError(err)
+		return r
+	}
+
+	go func() {
+		run := cmd.newRun(matcher, w)
+		run.names = !cmd.opts.h
+		if cmd.opts.treeOutput {
+			run.tree = newTree(root)
+		}
+		if err := run.scanTree(root, types); err != nil {
+			run.close(w, err)
+			return
+		}
+		run.close(w, run.finish())
+	}()
+
+	return r
+}
+
+// errStopWalk stops a walk once the search is done with it.
+var errStopWalk = errors.New("grep: stop walk")
+
+// scanTree scans every regular file in the tree rooted at root that types
+// selects, skipping the directories types does not select. Symbolic links are
+// only followed with WithDereferenceRecursive.
+func (run *run) scanTree(root string, types *typeFilter) error {
+	if run.cmd.opts.R {
+		return run.scanLinkedTree(root, nil, types)
+	}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return run.fileError(err)
+		}
+		if run.stopped() {
+			return errStopWalk
+		}
+		if d.IsDir() && path != root && !types.selectsDir(path) {
+			return filepath.SkipDir
+		}
+		if !types.selects(path) {
+			return nil
+		}
+		return run.scanWalkedFile(path, d.Type())
+	})
+	if err == errStopWalk {
+		return nil
+	}
+	return err
+}
+
+// scanFile writes the selected lines of the file at path, carrying on past
+// any error opening or reading it.
+func (run *run) scanFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return run.fileError(err)
+	}
+	defer f.Close()
+	err = run.scan(NamedReader{Name: path, Reader: f})
+	if pathErr, ok := err.(*os.PathError); ok && pathErr.Path == path {
+		return run.fileError(err)
+	}
+	return err
+}
+
+// scanLinkedTree is scanTree following symbolic links, where ancestors are
+// the directories that path is in. A directory that is one of its own
+// ancestors is skipped rather than walked forever.
+func (run *run) scanLinkedTree(path string, ancestors []os.FileInfo, types *typeFilter) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return run.fileError(err)
+	}
+	if !info.IsDir() {
+		if !types.selects(path) {
+			return nil
+		}
+		return run.scanWalkedFile(path, info.Mode())
+	}
+	if len(ancestors) > 0 && !types.selectsDir(path) {
+		return nil
+	}
+	for _, ancestor := range ancestors {
+		if os.SameFile(info, ancestor) {
+			run.skip(fmt.Errorf("grep: %s: recursive directory loop", path))
+			return nil
+		}
+	}
+
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return run.fileError(err)
+	}
+	ancestors = append(ancestors, info)
+	for _, entry := range entries {
+		if run.stopped() {
+			return nil
+		}
+		if err := run.scanLinkedTree(filepath.Join(path, entry.Name()), ancestors, types); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scanWalkedFile scans the file at path, found by walking a tree, if it is a
+// regular file or, with WithDevices("read"), a device. Binary files are
+// skipped unless WithBinaryFiles says how to search them.
+func (run *run) scanWalkedFile(path string, mode os.FileMode) error {
+	switch {
+	case isDevice(mode) && run.cmd.opts.devices == "read":
+		return run.scanFile(path)
+	case !mode.IsRegular():
+		return nil
+	case run.cmd.opts.binaryFiles != "":
+		return run.scanFile(path)
+	}
+	return run.scanTextFile(path)
+}
+
+// isDevice reports whether mode is that of a device, FIFO or socket.
+func isDevice(mode os.FileMode) bool {
+	return mode&(os.ModeDevice|os.ModeCharDevice|os.ModeNamedPipe|os.ModeSocket) != 0
+}
+
+// binarySniffSize is how much of a file scanTextFile reads to tell whether it
+// is binary.
+const binarySniffSize = 32 * 1024
+
+// scanTextFile is scanFile, except that files with a NUL byte near their
+// start are taken to be binary and skipped, as they are rarely worth
+// searching when walking a tree.
+func (run *run) scanTextFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return run.fileError(err)
+	}
+	defer f.Close()
+
+	head := make([]byte, binarySniffSize)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	head = head[:n]
+	if bytes.IndexByte(head, 0) >= 0 {
+		return nil
+	}
+	return run.scan(NamedReader{Name: path, Reader: io.MultiReader(bytes.NewReader(head), f)})
+}
@@ -0,0 +1,342 @@
+package grep_test
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kevin-cantwell/usrbin/pkg/grep"
+)
+
+// tree creates the files, keyed by slash-separated path, in a new temporary
+// directory and returns its path.
+func tree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "grep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestWithMaxPerFile_ReadDir(t *testing.T) {
+	files := map[string]string{}
+	for _, name := range []string{"a.log", "b.log", "sub/c.log"} {
+		files[name] = strings.Repeat("ERROR\n", 100)
+	}
+	dir := tree(t, files)
+	defer os.RemoveAll(dir)
+
+	out := grep.New("ERROR", grep.WithMaxPerFile(3)).ReadDir(dir)
+
+	body, err := ioutil.ReadAll(out)
+	if err != nil {
+		t.Fatalf("got err: %#v", err)
+	}
+	for _, name := range []string{"a.log", "b.log", "sub/c.log"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if n := strings.Count(string(body), path+":"); n != 3 {
+			t.Errorf("%s: got %d lines want 3", name, n)
+		}
+	}
+	if n := strings.Count(string(body), "\n"); n != 9 {
+		t.Errorf("got %d lines want %d", n, 9)
+	}
+}
+
+func TestWithCount_ReadDir(t *testing.T) {
+	dir := tree(t, map[string]string{
+		"a.log": "ERROR 1\nok\nERROR 2\n",
+		"b.log": "ok\n",
+	})
+	defer os.RemoveAll(dir)
+
+	out := grep.New("ERROR", grep.WithCount()).ReadDir(dir)
+
+	body, err := ioutil.ReadAll(out)
+	if err != nil {
+		t.Fatalf("got err: %#v", err)
+	}
+	want := filepath.Join(dir, "a.log") + ":2\n" + filepath.Join(dir, "b.log") + ":0\n"
+	if string(body) != want {
+		t.Errorf("got %q want %q", body, want)
+	}
+}
+
+func TestWithRecursive(t *testing.T) {
+	dir := tree(t, map[string]string{
+		"a.log":           "ERROR 1\nok\n",
+		"b.log":           "ok\n",
+		"sub/c.log":       "ok\nERROR 2\n",
+		"sub/deep/d.log":  "ERROR 3\n",
+		"sub/deep/e.log":  "ok\n",
+		"bin/data.bin":    "ERROR 4\x00\x01\n",
+		"outside/f.log":   "ERROR 5\n",
+		"outside/g/h.log": "ERROR 6\n",
+	})
+	defer os.RemoveAll(dir)
+	root := filepath.Join(dir, "root")
+	if err := os.Mkdir(root, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.log", "b.log", "sub", "bin"} {
+		if err := os.Rename(filepath.Join(dir, name), filepath.Join(root, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// symbolic links are left alone under -r
+	if err := os.Symlink(filepath.Join(dir, "outside", "g"), filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "outside", "f.log"), filepath.Join(root, "f.log")); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		out  string
+	}{
+		{
+			name: "directory",
+			args: []string{root},
+			out: filepath.Join(root, "a.log") + ":ERROR 1\n" +
+				filepath.Join(root, "sub", "c.log") + ":ERROR 2\n" +
+				filepath.Join(root, "sub", "deep", "d.log") + ":ERROR 3\n",
+		},
+		{
+			name: "working directory",
+			out:  "a.log:ERROR 1\n" + filepath.Join("sub", "c.log") + ":ERROR 2\n" + filepath.Join("sub", "deep", "d.log") + ":ERROR 3\n",
+		},
+		{
+			name: "file",
+			args: []string{"a.log"},
+			out:  "a.log:ERROR 1\n",
+		},
+		{
+			name: "directory and file",
+			args: []string{filepath.Join("sub", "deep"), "a.log"},
+			out:  filepath.Join("sub", "deep", "d.log") + ":ERROR 3\na.log:ERROR 1\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := ioutil.ReadAll(grep.New("ERROR", grep.WithRecursive()).Exec(tt.args))
+			if err != nil {
+				t.Fatalf("got err: %#v", err)
+			}
+			if string(body) != tt.out {
+				t.Fatalf("got %q want %q", body, tt.out)
+			}
+		})
+	}
+}
+
+func TestWithDereferenceRecursive(t *testing.T) {
+	dir := tree(t, map[string]string{
+		"root/a.log":      "ERROR 1\n",
+		"root/sub/b.log":  "ok\nERROR 2\n",
+		"outside/c.log":   "ERROR 3\n",
+		"outside/d/e.log": "ERROR 4\n",
+	})
+	defer os.RemoveAll(dir)
+	root := filepath.Join(dir, "root")
+	for link, target := range map[string]string{
+		"root/c.log":    "outside/c.log",
+		"root/d":        "outside/d",
+		"root/sub/loop": "root",
+	} {
+		if err := os.Symlink(filepath.Join(dir, target), filepath.Join(dir, filepath.FromSlash(link))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := filepath.Join(root, "a.log") + ":ERROR 1\n" +
+		filepath.Join(root, "c.log") + ":ERROR 3\n" +
+		filepath.Join(root, "d", "e.log") + ":ERROR 4\n" +
+		filepath.Join(root, "sub", "b.log") + ":ERROR 2\n"
+	for name, out := range map[string]func(*grep.Grep) io.Reader{
+		"Exec":    func(cmd *grep.Grep) io.Reader { return cmd.Exec([]string{root}) },
+		"ReadDir": func(cmd *grep.Grep) io.Reader { return cmd.ReadDir(root) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			body, err := ioutil.ReadAll(out(grep.New("ERROR", grep.WithDereferenceRecursive())))
+			if err == nil || !strings.Contains(err.Error(), "recursive directory loop") {
+				t.Fatalf("got err %v want a recursive directory loop", err)
+			}
+			if !strings.Contains(err.Error(), filepath.Join(root, "sub", "loop")) {
+				t.Fatalf("got err %v want it to name the loop", err)
+			}
+			if string(body) != want {
+				t.Fatalf("got %q want %q", body, want)
+			}
+		})
+	}
+}
+
+func TestWithInclude(t *testing.T) {
+	dir := tree(t, map[string]string{
+		"main.go":            "ERROR 1\n",
+		"main_test.go":       "ERROR 2\n",
+		"README.md":          "ERROR 3\n",
+		"pkg/lib.go":         "ERROR 4\n",
+		"pkg/lib_test.go":    "ERROR 5\n",
+		"vendor/dep/dep.go":  "ERROR 6\n",
+		"pkg/vendor/vend.go": "ERROR 7\n",
+	})
+	defer os.RemoveAll(dir)
+	path := func(name string) string { return filepath.Join(dir, filepath.FromSlash(name)) }
+
+	tests := []struct {
+		name string
+		opts []grep.Option
+		out  []string
+	}{
+		{
+			name: "WithInclude",
+			opts: []grep.Option{grep.WithInclude("*.go")},
+			out:  []string{"main.go", "main_test.go", "pkg/lib.go", "pkg/lib_test.go", "pkg/vendor/vend.go", "vendor/dep/dep.go"},
+		},
+		{
+			name: "several WithInclude",
+			opts: []grep.Option{grep.WithInclude("*.md"), grep.WithInclude("lib*")},
+			out:  []string{"README.md", "pkg/lib.go", "pkg/lib_test.go"},
+		},
+		{
+			name: "WithExclude",
+			opts: []grep.Option{grep.WithExclude("*_test.go"), grep.WithExclude("*.md")},
+			out:  []string{"main.go", "pkg/lib.go", "pkg/vendor/vend.go", "vendor/dep/dep.go"},
+		},
+		{
+			name: "WithExcludeDir",
+			opts: []grep.Option{grep.WithExcludeDir("vendor")},
+			out:  []string{"README.md", "main.go", "main_test.go", "pkg/lib.go", "pkg/lib_test.go"},
+		},
+		{
+			name: "all",
+			opts: []grep.Option{grep.WithInclude("*.go"), grep.WithExclude("*_test.go"), grep.WithExcludeDir("vendor")},
+			out:  []string{"main.go", "pkg/lib.go"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var want strings.Builder
+			for _, name := range tt.out {
+				want.WriteString(path(name) + "\n")
+			}
+			for _, follow := range []bool{false, true} {
+				opts := append([]grep.Option{grep.WithFilesWithMatches()}, tt.opts...)
+				if follow {
+					opts = append(opts, grep.WithDereferenceRecursive())
+				}
+				body, err := ioutil.ReadAll(grep.New("ERROR", opts...).ReadDir(dir))
+				if err != nil {
+					t.Fatalf("got err: %#v", err)
+				}
+				if string(body) != want.String() {
+					t.Fatalf("following links %v: got %q want %q", follow, body, want.String())
+				}
+			}
+		})
+	}
+}
+
+func TestWithExcludeFrom(t *testing.T) {
+	dir := tree(t, map[string]string{
+		"src/main.go":      "ERROR 1\n",
+		"src/main_test.go": "ERROR 2\n",
+		"src/notes.txt":    "ERROR 3\n",
+		"src/app.log":      "ERROR 4\n",
+		"src/data.csv":     "ERROR 5\n",
+		"excludes":         "*_test.go\n\n*.log\n*.txt\n",
+	})
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "src")
+
+	excludes, err := os.Open(filepath.Join(dir, "excludes"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer excludes.Close()
+
+	cmd := grep.New("ERROR", grep.WithFilesWithMatches(), grep.WithExcludeFrom(excludes), grep.WithExclude("*.csv"))
+	body, err := ioutil.ReadAll(cmd.ReadDir(src))
+	if err != nil {
+		t.Fatalf("got err: %#v", err)
+	}
+	if want := filepath.Join(src, "main.go") + "\n"; string(body) != want {
+		t.Fatalf("got %q want %q", body, want)
+	}
+}
+
+func TestWithExcludeFrom_readError(t *testing.T) {
+	dir := tree(t, map[string]string{"a.log": "ERROR 1\n", "excludes": "*.txt\n"})
+	defer os.RemoveAll(dir)
+
+	excludes, err := os.Open(filepath.Join(dir, "excludes"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	excludes.Close()
+
+	body, err := ioutil.ReadAll(grep.New("ERROR", grep.WithExcludeFrom(excludes)).ReadDir(dir))
+	if !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("got err %#v want %#v", err, os.ErrClosed)
+	}
+	if len(body) != 0 {
+		t.Fatalf("got %q want no output", body)
+	}
+}
+
+func TestWithBinaryFiles_ReadDir(t *testing.T) {
+	dir := tree(t, map[string]string{
+		"a.log":    "ERROR 1\n",
+		"data.bin": "ERROR 2\x00\n",
+	})
+	defer os.RemoveAll(dir)
+	a, bin := filepath.Join(dir, "a.log"), filepath.Join(dir, "data.bin")
+
+	tests := []struct {
+		name string
+		opts []grep.Option
+		out  string
+	}{
+		{"default", nil, a + ":ERROR 1\n"},
+		{"binary", []grep.Option{grep.WithBinaryFiles("binary")}, a + ":ERROR 1\nBinary file " + bin + " matches\n"},
+		{"text", []grep.Option{grep.WithText()}, a + ":ERROR 1\n" + bin + ":ERROR 2\x00\n"},
+		{"without-match", []grep.Option{grep.WithBinaryWithoutMatch()}, a + ":ERROR 1\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := ioutil.ReadAll(grep.New("ERROR", tt.opts...).ReadDir(dir))
+			if err != nil {
+				t.Fatalf("got err: %#v", err)
+			}
+			if string(body) != tt.out {
+				t.Fatalf("got %q want %q", body, tt.out)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package grep
+
+import (
+	"fmt"
+	"io"
+)
+
+// DistinctCount returns the number of distinct values captured by group
+// across every match in input, like grep -oP | sort -u | wc -l. Group 0 is
+// the whole match. Matches in which group does not participate are ignored.
+func (cmd *Grep) DistinctCount(input io.Reader, group int) (int, error) {
+	matcher, err := cmd.allMatcher()
+	if err != nil {
+		return 0, err
+	}
+	if group < 0 || !matcher.hasGroup(group) {
+		return 0, fmt.Errorf("grep: no pattern has capture group %d", group)
+	}
+
+	distinct := map[string]struct{}{}
+	s := matcher.newScanner(input)
+	for s.Scan() {
+		line := s.Bytes()
+		if !matcher.Match(line) {
+			continue
+		}
+		matcher.captures(line, group, func(capture []byte) {
+			distinct[string(capture)] = struct{}{}
+		})
+	}
+	if err := s.Err(); err != nil {
+		return 0, err
+	}
+	return len(distinct), nil
+}
+
+// hasGroup reports whether any pattern has capture group group.
+func (ms matchAll) hasGroup(group int) bool {
+	for _, m := range ms.each {
+		if group <= m.regexp.NumSubexp() {
+			return true
+		}
+	}
+	return false
+}
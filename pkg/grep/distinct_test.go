@@ -0,0 +1,111 @@
+package grep_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/kevin-cantwell/usrbin/pkg/grep"
+)
+
+func TestGrep_DistinctCount(t *testing.T) {
+	errRead := errors.New("read failed")
+
+	tests := []struct {
+		name    string
+		pattern string
+		opts    []grep.Option
+		group   int
+		in      io.Reader
+		count   int
+		err     bool
+	}{
+		{
+			name:    "ips",
+			pattern: `from (\d+\.\d+\.\d+\.\d+)`,
+			group:   1,
+			in: strings.NewReader("GET / from 10.0.0.1\n" +
+				"GET /a from 10.0.0.2\n" +
+				"GET /b from 10.0.0.1\n" +
+				"POST /c from 10.0.0.3\n" +
+				"GET / from 10.0.0.2"),
+			count: 3,
+		},
+		{
+			name:    "several-per-line",
+			pattern: `id=(\w+)`,
+			group:   1,
+			in:      strings.NewReader("id=a id=b\nid=b id=c id=a"),
+			count:   3,
+		},
+		{
+			name:    "whole-match",
+			pattern: `[a-z]+`,
+			group:   0,
+			in:      strings.NewReader("foo bar\nfoo baz foo"),
+			count:   3,
+		},
+		{
+			name:    "optional-group",
+			pattern: `x(\d)?`,
+			group:   1,
+			in:      strings.NewReader("x1 x x2\nx1"),
+			count:   2,
+		},
+		{
+			name:    "WithIgnoreCase",
+			pattern: `user=(\w+)`,
+			opts:    []grep.Option{grep.WithIgnoreCase()},
+			group:   1,
+			in:      strings.NewReader("USER=bob\nuser=bob\nuser=Bob"),
+			count:   2,
+		},
+		{
+			name:  "WithRegexps",
+			opts:  []grep.Option{grep.WithRegexps(`a=(\w+)`, `b=(\w+)`)},
+			group: 1,
+			in:    strings.NewReader("a=1\nb=1\nb=2"),
+			count: 2,
+		},
+		{
+			name:    "none",
+			pattern: `id=(\w+)`,
+			group:   1,
+			in:      strings.NewReader("foo\nbar"),
+			count:   0,
+		},
+		{
+			name:    "no-such-group",
+			pattern: `id=(\w+)`,
+			group:   2,
+			in:      strings.NewReader("id=a"),
+			err:     true,
+		},
+		{
+			name:    "bad-pattern",
+			pattern: `(`,
+			group:   0,
+			in:      strings.NewReader("foo"),
+			err:     true,
+		},
+		{
+			name:    "read-error",
+			pattern: `id=(\w+)`,
+			group:   1,
+			in:      &failingReader{data: "id=a\n", err: errRead},
+			err:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, err := grep.New(tt.pattern, tt.opts...).DistinctCount(tt.in, tt.group)
+			if count != tt.count {
+				t.Errorf("got %d want %d", count, tt.count)
+			}
+			if (err != nil) != tt.err {
+				t.Errorf("got err %v", err)
+			}
+		})
+	}
+}
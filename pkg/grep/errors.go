@@ -0,0 +1,48 @@
+package grep
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PatternError reports a pattern that does not compile. A search, or
+// Validate, fails with one for the first bad pattern.
+type PatternError struct {
+	// the pattern as given, before any translation
+	Pattern string
+	Err     error
+}
+
+func (e *PatternError) Error() string {
+	return fmt.Sprintf("grep: pattern %q: %s", e.Pattern, strings.TrimPrefix(e.Err.Error(), "grep: "))
+}
+
+func (e *PatternError) Unwrap() error {
+	return e.Err
+}
+
+// OptionConflictError reports Options that cannot be used together, such as
+// WithFixedStrings and WithBasicRegexp. A search, or Validate, fails with one
+// before reading any input.
+type OptionConflictError struct {
+	// the names of the conflicting Option funcs
+	Options [2]string
+}
+
+func (e *OptionConflictError) Error() string {
+	return fmt.Sprintf("grep: %s conflicts with %s", e.Options[0], e.Options[1])
+}
+
+// UnsupportedSyntaxError reports a Perl construct in a pattern that Go's
+// regexp package, which never backtracks, does not support. A PatternError
+// wraps it.
+type UnsupportedSyntaxError struct {
+	// what the construct is, such as "lookahead"
+	Feature string
+	// the construct as written, such as "(?="
+	Construct string
+}
+
+func (e *UnsupportedSyntaxError) Error() string {
+	return fmt.Sprintf("grep: %s %s is not supported", e.Feature, e.Construct)
+}
@@ -0,0 +1,9 @@
+package grep
+
+// WithoutCombining tests each pattern on its own, as the baseline the
+// combined alternation of every pattern is checked against.
+func WithoutCombining() Option {
+	return func(opts *Opts) {
+		opts.noCombine = true
+	}
+}
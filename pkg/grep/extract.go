@@ -0,0 +1,61 @@
+package grep
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// Extract writes capture group group of every match in input to out, one per
+// line, like grep -oP with \K but for any group. Group 0 is the whole match.
+// Matches in which group does not participate are skipped.
+func (cmd *Grep) Extract(input io.Reader, group int, out io.Writer) error {
+	matcher, err := cmd.allMatcher()
+	if err != nil {
+		return err
+	}
+	if group < 0 || !matcher.hasGroup(group) {
+		return fmt.Errorf("grep: no pattern has capture group %d", group)
+	}
+
+	w := bufio.NewWriter(out)
+	s := matcher.newScanner(input)
+	for s.Scan() {
+		line := s.Bytes()
+		if !matcher.Match(line) {
+			continue
+		}
+		var werr error
+		matcher.captures(line, group, func(capture []byte) {
+			if werr == nil {
+				w.Write(capture)
+				werr = w.WriteByte('\n')
+			}
+		})
+		if werr != nil {
+			return werr
+		}
+	}
+	if err := s.Err(); err != nil {
+		// keep whatever was extracted before the input failed
+		w.Flush()
+		return err
+	}
+	return w.Flush()
+}
+
+// captures calls fn with the text of group in each match in line, in the
+// order of the patterns, skipping matches in which group does not participate.
+func (ms matchAll) captures(line []byte, group int, fn func([]byte)) {
+	for _, m := range ms.each {
+		if group > m.regexp.NumSubexp() {
+			continue
+		}
+		for _, i := range m.regexp.FindAllSubmatchIndex(line, -1) {
+			if i[2*group] < 0 || !m.accept(line, i[0], i[1]) {
+				continue
+			}
+			fn(line[i[2*group]:i[2*group+1]])
+		}
+	}
+}
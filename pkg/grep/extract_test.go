@@ -0,0 +1,92 @@
+package grep_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/kevin-cantwell/usrbin/pkg/grep"
+)
+
+func TestGrep_Extract(t *testing.T) {
+	errRead := errors.New("read failed")
+
+	tests := []struct {
+		name    string
+		pattern string
+		opts    []grep.Option
+		group   int
+		in      io.Reader
+		out     string
+		err     bool
+	}{
+		{
+			name:    "ids",
+			pattern: `order #(\d+)`,
+			group:   1,
+			in: strings.NewReader("shipped order #1042\n" +
+				"no orders today\n" +
+				"order #7 and order #19 cancelled"),
+			out: "1042\n7\n19\n",
+		},
+		{
+			name:    "whole-match",
+			pattern: `#\d+`,
+			group:   0,
+			in:      strings.NewReader("#1 #2\nnone"),
+			out:     "#1\n#2\n",
+		},
+		{
+			name:    "optional-group",
+			pattern: `id(=\d+)?`,
+			group:   1,
+			in:      strings.NewReader("id=1 id id=3"),
+			out:     "=1\n=3\n",
+		},
+		{
+			name:    "WithInvertMatch",
+			pattern: `id=(\d+)`,
+			opts:    []grep.Option{grep.WithInvertMatch()},
+			group:   1,
+			in:      strings.NewReader("id=1\nfoo"),
+			out:     "",
+		},
+		{
+			name:    "WithWordRegexp",
+			pattern: `id=(\d+)`,
+			opts:    []grep.Option{grep.WithWordRegexp()},
+			group:   1,
+			in:      strings.NewReader("id=1 xid=2"),
+			out:     "1\n",
+		},
+		{
+			name:    "no-such-group",
+			pattern: `id=(\d+)`,
+			group:   2,
+			in:      strings.NewReader("id=1"),
+			err:     true,
+		},
+		{
+			name:    "read-error",
+			pattern: `id=(\d+)`,
+			group:   1,
+			in:      &failingReader{data: "id=1\n", err: errRead},
+			out:     "1\n",
+			err:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := grep.New(tt.pattern, tt.opts...).Extract(tt.in, tt.group, &out)
+			if got := out.String(); got != tt.out {
+				t.Errorf("got %q want %q", got, tt.out)
+			}
+			if (err != nil) != tt.err {
+				t.Errorf("got err %v", err)
+			}
+		})
+	}
+}
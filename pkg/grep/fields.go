@@ -0,0 +1,121 @@
+package grep
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// jsonValue returns the value at the dotted key path in the JSON object line.
+// String values are returned unquoted and any other value as JSON. It returns
+// false if line is not JSON or has nothing at path.
+func jsonValue(line []byte, path string) ([]byte, bool) {
+	d := json.NewDecoder(bytes.NewReader(line))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return nil, false
+	}
+
+	for _, key := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]interface{}:
+			child, ok := node[key]
+			if !ok {
+				return nil, false
+			}
+			v = child
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			v = node[i]
+		default:
+			return nil, false
+		}
+	}
+
+	if s, ok := v.(string); ok {
+		return []byte(s), true
+	}
+	value, err := json.Marshal(v)
+	if err != nil {
+		return nil, false
+	}
+	return value, true
+}
+
+// logfmtValues returns the values of keys in the logfmt line, in the order
+// they appear in line.
+func logfmtValues(line []byte, keys []string) [][]byte {
+	var values [][]byte
+	for len(line) > 0 {
+		line = bytes.TrimLeft(line, " \t")
+		if len(line) == 0 {
+			break
+		}
+
+		// key runs until '=' or whitespace
+		end := bytes.IndexAny(line, " \t=")
+		if end < 0 {
+			end = len(line)
+		}
+		key := string(line[:end])
+		line = line[end:]
+
+		var value []byte
+		if len(line) > 0 && line[0] == '=' {
+			value, line = logfmtValue(line[1:])
+		}
+
+		for _, k := range keys {
+			if k == key {
+				values = append(values, value)
+				break
+			}
+		}
+	}
+	return values
+}
+
+// logfmtValue splits the value at the start of line from the rest of line.
+func logfmtValue(line []byte) ([]byte, []byte) {
+	if len(line) == 0 || line[0] != '"' {
+		end := bytes.IndexAny(line, " \t")
+		if end < 0 {
+			end = len(line)
+		}
+		return line[:end], line[end:]
+	}
+
+	var value []byte
+	for i := 1; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '\\' && i+1 < len(line):
+			i++
+			value = append(value, line[i])
+		case c == '"':
+			return value, line[i+1:]
+		default:
+			value = append(value, c)
+		}
+	}
+	// unterminated quote runs to the end of the line
+	return value, nil
+}
+
+// csvField returns field col, counting from 1, of the CSV record line,
+// unquoted. It returns false if line is not valid CSV or has fewer than col
+// fields.
+func csvField(line []byte, col int) ([]byte, bool) {
+	r := csv.NewReader(bytes.NewReader(line))
+	r.FieldsPerRecord = -1
+	record, err := r.Read()
+	if err != nil || col > len(record) {
+		return nil, false
+	}
+	return []byte(record[col-1]), true
+}
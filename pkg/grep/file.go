@@ -0,0 +1,56 @@
+package grep
+
+import (
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// ExecToFile writes the lines of input selected by cmd to the file at path.
+// Output goes to a temporary file in the same directory which is renamed into
+// place only once the search succeeds, so a failed search never leaves a
+// truncated file at path. On failure the temporary file is removed and any
+// existing file at path is left untouched. As with a shell redirect, an
+// existing file keeps its mode and a new one is created with mode 0666 less
+// the umask.
+func (cmd *Grep) ExecToFile(input io.Reader, path string) (err error) {
+	existing, statErr := os.Stat(path)
+	tmp, err := createTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp", 0666)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err := io.Copy(tmp, cmd.Read(input)); err != nil {
+		return err
+	}
+	if statErr == nil {
+		if err := tmp.Chmod(existing.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// createTemp creates a new file in dir, with a name that starts with prefix,
+// like ioutil.TempFile, but with mode perm less the umask rather than 0600.
+func createTemp(dir, prefix string, perm os.FileMode) (*os.File, error) {
+	for i := 0; ; i++ {
+		name := filepath.Join(dir, prefix+strconv.FormatUint(uint64(rand.Uint32()), 10))
+		f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, perm)
+		if os.IsExist(err) && i < 10000 {
+			continue
+		}
+		return f, err
+	}
+}
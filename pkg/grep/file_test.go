@@ -0,0 +1,115 @@
+package grep_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kevin-cantwell/usrbin/pkg/grep"
+)
+
+func TestGrep_ExecToFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "grep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "out")
+
+	err = grep.New("foo").ExecToFile(strings.NewReader("foo\nbar\nfoobar"), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "foo\nfoobar\n" {
+		t.Errorf("got %q want %q", out, "foo\nfoobar\n")
+	}
+	assertFiles(t, dir, "out")
+}
+
+func TestGrep_ExecToFile_error(t *testing.T) {
+	errRead := errors.New("read failed")
+
+	tests := []struct {
+		name    string
+		pattern string
+		in      *failingReader
+	}{
+		{
+			name:    "read-error",
+			pattern: "foo",
+			in:      &failingReader{data: "foo\nfoo\n", err: errRead},
+		},
+		{
+			name:    "bad-pattern",
+			pattern: "(",
+			in:      &failingReader{data: "foo\n", err: errRead},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "grep")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			path := filepath.Join(dir, "out")
+			if err := grep.New(tt.pattern).ExecToFile(tt.in, path); err == nil {
+				t.Fatal("got nil error")
+			}
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				t.Errorf("got %v want output file not to exist", err)
+			}
+			assertFiles(t, dir)
+		})
+	}
+}
+
+func TestGrep_ExecToFile_keepsExisting(t *testing.T) {
+	dir, err := ioutil.TempDir("", "grep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "out")
+	if err := ioutil.WriteFile(path, []byte("previous\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	in := &failingReader{data: "foo\n", err: errors.New("read failed")}
+	if err := grep.New("foo").ExecToFile(in, path); err == nil {
+		t.Fatal("got nil error")
+	}
+	out, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "previous\n" {
+		t.Errorf("got %q want %q", out, "previous\n")
+	}
+	assertFiles(t, dir, "out")
+}
+
+// assertFiles fails unless dir holds exactly the named files, which
+// catches leftover temporary files.
+func assertFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, info := range infos {
+		got = append(got, info.Name())
+	}
+	if strings.Join(got, ",") != strings.Join(names, ",") {
+		t.Errorf("got files %q want %q", got, names)
+	}
+}
@@ -0,0 +1,55 @@
+//go:build !windows
+// +build !windows
+
+package grep_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/kevin-cantwell/usrbin/pkg/grep"
+)
+
+func TestGrep_ExecToFile_mode(t *testing.T) {
+	defer syscall.Umask(syscall.Umask(022))
+	dir := t.TempDir()
+
+	tests := []struct {
+		name string
+		// the mode of the file before, if it exists
+		existing os.FileMode
+		want     os.FileMode
+	}{
+		{name: "new", want: 0644},
+		{name: "existing", existing: 0640, want: 0640},
+		{name: "existing-beyond-umask", existing: 0666, want: 0666},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name)
+			if tt.existing != 0 {
+				if err := ioutil.WriteFile(path, []byte("previous\n"), tt.existing); err != nil {
+					t.Fatal(err)
+				}
+				// WriteFile is subject to the umask
+				if err := os.Chmod(path, tt.existing); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := grep.New("foo").ExecToFile(strings.NewReader("foo\n"), path); err != nil {
+				t.Fatal(err)
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := info.Mode().Perm(); got != tt.want {
+				t.Errorf("got mode %v want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package grep
+
+import "io"
+
+// ForEach calls fn with the number and contents of each line of input
+// selected, without its terminator, stopping at the first error fn returns
+// and returning it. Lines are passed straight from the read buffer, with none
+// of the copying Read does to write them out, so line is only valid until fn
+// returns and must be copied to be kept. As with Read, the search stops once
+// WithMaxCount lines are selected, and with WithDiffFilter only the lines the
+// diff adds are considered, for an input that is a NamedReader or a file.
+func (cmd *Grep) ForEach(input io.Reader, fn func(lineNo int, line []byte) error) error {
+	matcher, err := cmd.allMatcher()
+	if err != nil {
+		return err
+	}
+
+	name := inputName(input)
+	var lineNo, selected int
+	s := matcher.newScanner(input)
+	for (cmd.opts.m < 0 || selected < cmd.opts.m) && s.Scan() {
+		lineNo++
+		line := s.Bytes()
+		if matcher.added != nil && !matcher.added.has(name, lineNo) {
+			continue
+		}
+		if !matcher.Match(line) {
+			continue
+		}
+		selected++
+		if err := fn(lineNo, line); err != nil {
+			return err
+		}
+	}
+	return s.Err()
+}
@@ -0,0 +1,146 @@
+package grep_test
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kevin-cantwell/usrbin/pkg/grep"
+)
+
+func TestGrep_ForEach(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		opts    []grep.Option
+		in      string
+		out     []string
+	}{
+		{
+			name:    "selected lines",
+			pattern: "foo",
+			in:      "foo 1\nbar\nfoo 3\r\n",
+			out:     []string{"1:foo 1", "3:foo 3"},
+		},
+		{
+			name:    "WithInvertMatch",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithInvertMatch()},
+			in:      "foo 1\nbar\nfoo 3\n",
+			out:     []string{"2:bar"},
+		},
+		{
+			name:    "WithMaxCount",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithMaxCount(1)},
+			in:      "foo 1\nbar\nfoo 3\n",
+			out:     []string{"1:foo 1"},
+		},
+		{
+			name:    "no match",
+			pattern: "baz",
+			in:      "foo 1\nbar\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			err := grep.New(tt.pattern, tt.opts...).ForEach(strings.NewReader(tt.in), func(lineNo int, line []byte) error {
+				got = append(got, fmt.Sprintf("%d:%s", lineNo, line))
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("got err: %#v", err)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.out) {
+				t.Fatalf("got %q want %q", got, tt.out)
+			}
+		})
+	}
+}
+
+func TestGrep_ForEach_WithDiffFilter(t *testing.T) {
+	cmd := grep.New("TODO", grep.WithDiffFilter(strings.NewReader(sampleDiff)))
+	input := grep.NamedReader{Name: "main.go", Reader: strings.NewReader(sampleFile)}
+
+	var got []string
+	err := cmd.ForEach(input, func(lineNo int, line []byte) error {
+		got = append(got, fmt.Sprintf("%d:%s", lineNo, line))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("got err: %#v", err)
+	}
+	want := []string{"2:// TODO new", "5:\tpanic(\"TODO\")", "12:\t// TODO added late"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("got %q want %q", got, want)
+	}
+}
+
+func TestGrep_ForEach_error(t *testing.T) {
+	want := errors.New("stop")
+	var calls int
+	err := grep.New("foo").ForEach(strings.NewReader("foo 1\nfoo 2\nfoo 3\n"), func(lineNo int, line []byte) error {
+		calls++
+		if lineNo == 2 {
+			return want
+		}
+		return nil
+	})
+	if err != want {
+		t.Fatalf("got err %#v want %#v", err, want)
+	}
+	if calls != 2 {
+		t.Fatalf("got %d calls want 2", calls)
+	}
+}
+
+func TestGrep_ForEach_badPattern(t *testing.T) {
+	err := grep.New("(").ForEach(strings.NewReader("foo\n"), func(int, []byte) error {
+		t.Fatal("fn was called")
+		return nil
+	})
+	if err == nil {
+		t.Fatal("got no error")
+	}
+}
+
+func BenchmarkGrep_ForEach(b *testing.B) {
+	var in strings.Builder
+	for i := 0; i < 10000; i++ {
+		fmt.Fprintf(&in, "%d the quick brown fox jumps over the lazy dog\n", i)
+		if i%10 == 0 {
+			in.WriteString("ERROR something went wrong here\n")
+		}
+	}
+	cmd := grep.New("ERROR")
+
+	b.Run("ForEach", func(b *testing.B) {
+		b.SetBytes(int64(in.Len()))
+		for i := 0; i < b.N; i++ {
+			var n int
+			err := cmd.ForEach(strings.NewReader(in.String()), func(lineNo int, line []byte) error {
+				n += len(line)
+				return nil
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("Read", func(b *testing.B) {
+		b.SetBytes(int64(in.Len()))
+		for i := 0; i < b.N; i++ {
+			var n int
+			s := bufio.NewScanner(cmd.Read(strings.NewReader(in.String())))
+			for s.Scan() {
+				n += len(s.Bytes())
+			}
+			if err := s.Err(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
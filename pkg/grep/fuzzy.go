@@ -0,0 +1,75 @@
+package grep
+
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxFuzzyPattern is the longest pattern, in runes, WithFuzzy accepts.
+const maxFuzzyPattern = 64
+
+// fuzzy matches lines containing a substring within k edits of a literal
+// pattern.
+type fuzzy struct {
+	pattern []rune
+	k       int
+	fold    bool
+}
+
+func newFuzzy(pattern string, k int, fold bool) (*fuzzy, error) {
+	f := &fuzzy{pattern: []rune(pattern), k: k, fold: fold}
+	if len(f.pattern) > maxFuzzyPattern {
+		return nil, fmt.Errorf("grep: fuzzy pattern longer than %d characters: %q", maxFuzzyPattern, pattern)
+	}
+	if f.fold {
+		for i, r := range f.pattern {
+			f.pattern[i] = unicode.ToLower(r)
+		}
+	}
+	return f, nil
+}
+
+// match reports whether some substring of line is within k edits of the
+// pattern. It walks line once, keeping a single column of the edit distance
+// table, where the distance of the empty pattern prefix is always zero so that
+// a match may begin anywhere.
+func (f *fuzzy) match(line []byte) bool {
+	m := len(f.pattern)
+	if m <= f.k {
+		return true
+	}
+
+	col := make([]int, m+1)
+	for i := range col {
+		col[i] = i
+	}
+
+	for len(line) > 0 {
+		r, size := utf8.DecodeRune(line)
+		line = line[size:]
+		if f.fold {
+			r = unicode.ToLower(r)
+		}
+
+		diag := col[0]
+		for i := 1; i <= m; i++ {
+			cost := 1
+			if f.pattern[i-1] == r {
+				cost = 0
+			}
+			next := diag + cost
+			if col[i]+1 < next {
+				next = col[i] + 1
+			}
+			if col[i-1]+1 < next {
+				next = col[i-1] + 1
+			}
+			diag, col[i] = col[i], next
+		}
+		if col[m] <= f.k {
+			return true
+		}
+	}
+	return false
+}
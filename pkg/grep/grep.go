@@ -7,46 +7,98 @@ package grep
 import (
 	"bufio"
 	"bytes"
+	"fmt"
 	"io"
+	"math/rand"
 	"os"
 	"regexp"
 	"regexp/syntax"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
 )
 
-// TODO: specific usage error types to communicate usage errors
-
-type Opt func(*Opts)
+// Option configures a Grep.
+type Option func(*Opts)
 
 // WithRegexps uses one or more patterns; newlines within patterns
-// separate each pattern from the next. If this Opt is used multiple times
-// or is combined with the WithFiles Opt, search for all patterns given.
-func WithRegexps(patterns ...string) Opt {
+// separate each pattern from the next. If this Option is used multiple times
+// or is combined with the WithFiles Option, search for all patterns given,
+// including any pattern passed to New.
+func WithRegexps(patterns ...string) Option {
 	return func(opts *Opts) {
 		opts.e = append(opts.e, patterns...)
 	}
 }
 
-// WithFiles obtain patterns from files, one per line. If this Opt is
-// combined with the WithRegexps Opt, search for all patterns given.
+// WithFiles obtain patterns from files, one per line. If this Option is
+// combined with the WithRegexps Option, search for all patterns given.
 // The empty file contains zero patterns, and therefore matches nothing.
-func WithFiles(files ...*os.File) Opt {
+func WithFiles(files ...*os.File) Option {
 	return func(opts *Opts) {
 		opts.f = append(opts.f, files...)
 	}
 }
 
+// WithFixedStrings interprets patterns as fixed strings to search for, rather
+// than as regular expressions, so that characters such as '.' and '*' only
+// match themselves. Newlines still separate one pattern from the next.
+func WithFixedStrings() Option {
+	return func(opts *Opts) {
+		opts.setSyntax(fixedStrings, "WithFixedStrings")
+	}
+}
+
+// WithBasicRegexp interprets patterns as POSIX basic regular expressions, as
+// GNU grep does by default. The characters + ? | ( ) { } only have their
+// special meaning when escaped, as in \(a\|b\)\{2\}. Backreferences are not
+// supported, and \< and \> match at either end of a word.
+func WithBasicRegexp() Option {
+	return func(opts *Opts) {
+		opts.setSyntax(basicRegexp, "WithBasicRegexp")
+	}
+}
+
+// WithExtendedRegexp interprets patterns as POSIX extended regular
+// expressions, as egrep does. Matching is leftmost-longest, so that a|ab
+// matches all of "ab". Perl extensions such as \d and (?i) are rejected, as
+// are backreferences.
+//
+// Of WithFixedStrings, WithBasicRegexp, WithExtendedRegexp and
+// WithPerlRegexp, whichever is applied last wins, except that WithFixedStrings
+// and WithBasicRegexp together fail the search with an *OptionConflictError.
+// Without any of them, patterns use Go's Perl-like syntax.
+func WithExtendedRegexp() Option {
+	return func(opts *Opts) {
+		opts.setSyntax(extendedRegexp, "WithExtendedRegexp")
+	}
+}
+
+// WithPerlRegexp interprets patterns as Perl-like regular expressions, in
+// the syntax of Go's regexp package, which is the default. Go's regexp never
+// backtracks, so Perl's backreferences, lookarounds, atomic groups and
+// possessive quantifiers are rejected with an *UnsupportedSyntaxError;
+// WithNotPrecededBy and WithNotFollowedBy stand in for negative lookarounds.
+func WithPerlRegexp() Option {
+	return func(opts *Opts) {
+		opts.setSyntax(perlRegexp, "WithPerlRegexp")
+	}
+}
+
 // WithIgnoreCase ignores case distinctions, so that characters that differ
-// only in case match each other. Setting this Opt is identical to specifying
-// a case-insensitive flag in pattern.
-func WithIgnoreCase() Opt {
+// only in case match each other. Setting this Option is identical to
+// specifying a case-insensitive flag in pattern.
+func WithIgnoreCase() Option {
 	return func(opts *Opts) {
 		opts.i = true
 	}
 }
 
 // WithInvertMatch inverts the sense of matching, to select non-matching lines.
-func WithInvertMatch() Opt {
+func WithInvertMatch() Option {
 	return func(opts *Opts) {
 		opts.v = true
 	}
@@ -57,8 +109,8 @@ func WithInvertMatch() Opt {
 // of the line, or preceded by a non-word constituent character. Similarly, it
 // must be either at the end of the line or followed by a non-word constituent
 // character. Word constituent characters are letters, digits, and the underscore.
-// This Opt has no effect if WithLineRegexp is also specified.
-func WithWordRegexp() Opt {
+// This Option has no effect if WithLineRegexp is also specified.
+func WithWordRegexp() Option {
 	return func(opts *Opts) {
 		if opts.x {
 			return
@@ -70,17 +122,838 @@ func WithWordRegexp() Opt {
 // WithLineRegexp selects only those matches that exactly match the whole line.
 // For regular expression patterns, this is like parenthesizing each pattern and
 // then surrounding it with ‘^’ and ‘$’.
-func WithLineRegexp() Opt {
+func WithLineRegexp() Option {
 	return func(opts *Opts) {
 		opts.x = true
 		opts.w = false
 	}
 }
 
+// WithNullData treats input and output data as sequences of records, each
+// terminated by a zero byte (the ASCII NUL character) instead of a newline.
+func WithNullData() Option {
+	return func(opts *Opts) {
+		opts.z = true
+	}
+}
+
+// WithByteOffset prefixes each output record with the 0-based byte offset
+// within the input of the start of the record, followed by a colon. Offsets
+// count bytes, not runes, including those of the terminators stripped from
+// preceding records, so they agree with GNU grep.
+func WithByteOffset() Option {
+	return func(opts *Opts) {
+		opts.b = true
+	}
+}
+
+// WithLineNumber prefixes each output record with its 1-based line number
+// within its input, followed by a colon.
+func WithLineNumber() Option {
+	return func(opts *Opts) {
+		opts.n = true
+	}
+}
+
+// WithMaxCount stops the search after n selected lines, and any trailing
+// context the last of them has, without reading further input. With
+// WithInvertMatch, the non-matching lines are the ones counted. A count of 0
+// selects nothing, without reading any input, and a negative count means no
+// limit, which is the default.
+func WithMaxCount(n int) Option {
+	return func(opts *Opts) {
+		opts.m = n
+	}
+}
+
+// WithOnlyMatching prints each non-empty match in a selected line on a line
+// of its own, in the order they appear, rather than the whole line. Any prefix,
+// such as from WithLineNumber, is repeated for each match; WithByteOffset
+// gives the offset of the match. Lines selected by WithInvertMatch have no
+// matches, so nothing is printed for them.
+func WithOnlyMatching() Option {
+	return func(opts *Opts) {
+		opts.o = true
+	}
+}
+
+// WithFilename prefixes each output line with the name of its input followed
+// by a colon, even when there is only one input. Of WithFilename and
+// WithNoFilename, whichever is applied last wins.
+func WithFilename() Option {
+	return func(opts *Opts) {
+		opts.H = true
+		opts.h = false
+	}
+}
+
+// WithNoFilename never prefixes output lines with the name of their input,
+// even when there are several inputs. Of WithFilename and WithNoFilename,
+// whichever is applied last wins.
+func WithNoFilename() Option {
+	return func(opts *Opts) {
+		opts.h = true
+		opts.H = false
+	}
+}
+
+// WithLabel prints label as the name of inputs that have none, such as the
+// one given to Read, instead of "(standard input)". Names are only printed
+// when there are several inputs or WithFilename is set.
+func WithLabel(label string) Option {
+	return func(opts *Opts) {
+		opts.label = label
+	}
+}
+
+// WithAfterContext includes n lines of trailing context after selected lines.
+// As with GNU grep, overlapping and adjacent windows of context are merged into
+// blocks, so that each line is printed at most once, and blocks are separated
+// by a "--" line. The prefixes of context lines end in '-' rather than ':'.
+func WithAfterContext(n int) Option {
+	return func(opts *Opts) {
+		opts.A = n
+	}
+}
+
+// WithBeforeContext includes n lines of leading context before selected lines,
+// printed as for WithAfterContext.
+func WithBeforeContext(n int) Option {
+	return func(opts *Opts) {
+		opts.B = n
+	}
+}
+
+// WithContext includes n lines of leading and trailing context around
+// selected lines. It is equivalent to WithBeforeContext(n) and
+// WithAfterContext(n) together.
+func WithContext(n int) Option {
+	return func(opts *Opts) {
+		opts.A = n
+		opts.B = n
+	}
+}
+
+// WithColor highlights matches in output lines with ANSI escape sequences,
+// as GNU grep does with the default GREP_COLORS. when is "always" to
+// highlight, "never" not to, or "auto" to highlight only if the output is
+// going to a terminal. Since output is an io.Reader, the package cannot tell
+// where it ends up, so "auto" relies on WithTerminal and does not highlight
+// without it. Escape sequences are not counted in byte offsets.
+func WithColor(when string) Option {
+	return func(opts *Opts) {
+		opts.colorWhen = when
+	}
+}
+
+// WithTerminal tells WithColor("auto") whether output is going to a
+// terminal, as a caller can tell by checking the file it copies output to.
+func WithTerminal(isTTY bool) Option {
+	return func(opts *Opts) {
+		opts.isTTY = isTTY
+	}
+}
+
+// WithCount suppresses normal output and instead prints, for each input, a
+// count of the lines selected in it, prefixed with the name of the input when
+// names are printed. With WithInvertMatch, non-matching lines are counted.
+func WithCount() Option {
+	return func(opts *Opts) {
+		opts.c = true
+	}
+}
+
+// WithLineBuffered makes each line of output readable as soon as it is
+// written, rather than once WithOutputBufferSize has buffered enough. This
+// holds for every kind of output line, including counts, names, context and
+// the matches printed by WithOnlyMatching.
+func WithLineBuffered() Option {
+	return func(opts *Opts) {
+		opts.lineBuffered = true
+	}
+}
+
+// WithBloomPrefilter rejects lines that cannot match before running the full
+// matcher, using a bloom filter over the leading n-gram of each pattern. It
+// pays off for very large sets of literal patterns (e.g. tens of thousands
+// given by WithFiles) on input where most lines don't match, at the cost of a
+// little memory. The prefilter only engages when every pattern is a literal
+// string and case is not ignored; otherwise it is silently skipped.
+func WithBloomPrefilter() Option {
+	return func(opts *Opts) {
+		opts.bloom = true
+	}
+}
+
+// WithSectionBy groups the selected lines into sections, one per distinct
+// value of capture group group, printed under a "== VALUE ==" header in order
+// of each value's first appearance. Group 0 is the whole match. Lines for which
+// the group did not participate in the match, including every line selected by
+// WithInvertMatch, are grouped under an empty header. Since no section is
+// complete until the input is, all output is buffered until EOF.
+func WithSectionBy(group int) Option {
+	return func(opts *Opts) {
+		opts.sectioned = true
+		opts.sectionBy = group
+	}
+}
+
+// WithJSONPath treats each line as a JSON object, as in newline-delimited JSON
+// logs, and matches patterns only against the value at the dotted key path,
+// e.g. "message" or "req.url". Array elements are addressed by index. String
+// values are matched without their quotes; any other value is matched as JSON.
+// Selected lines are printed whole. Lines that are not JSON or have no value
+// at path are never selected, not even by WithInvertMatch.
+func WithJSONPath(path string) Option {
+	return func(opts *Opts) {
+		opts.jsonPath = path
+	}
+}
+
+// WithTotalOnly suppresses normal output and instead prints a single count of
+// the lines selected across all inputs, rather than a count per input.
+func WithTotalOnly() Option {
+	return func(opts *Opts) {
+		opts.totalOnly = true
+	}
+}
+
+// WithFuzzy selects lines containing a substring within maxEdits insertions,
+// deletions or substitutions of a pattern, like agrep. Patterns are taken
+// literally rather than as regular expressions and may be at most 64
+// characters long. WithIgnoreCase is honored; WithWordRegexp and
+// WithLineRegexp are not. Each line costs time proportional to its length
+// times the pattern's, so fuzzy matching is much slower than regexp matching.
+func WithFuzzy(maxEdits int) Option {
+	return func(opts *Opts) {
+		opts.fuzzy = true
+		opts.maxEdits = maxEdits
+	}
+}
+
+// WithLogfmt treats each line as logfmt, a sequence of key=value pairs such
+// as `level=info msg="user logged in"`, and matches patterns only against the
+// values of the named keys. A line is selected if the value of any of keys
+// matches, and is printed whole. Values may be double-quoted to contain
+// spaces, with backslash escaping a quote. A key without a value has the
+// empty value. Lines with none of keys are never selected, not even by
+// WithInvertMatch.
+func WithLogfmt(keys ...string) Option {
+	return func(opts *Opts) {
+		opts.logfmtKeys = append(opts.logfmtKeys, keys...)
+	}
+}
+
+// WithCSVField treats each line as a CSV record and matches patterns only
+// against field col, counting from 1 as cut -f does. Fields may be
+// double-quoted to contain commas, with a doubled quote standing for a quote.
+// Selected lines are printed whole. Lines that are not valid CSV or have
+// fewer than col fields are never selected, not even by WithInvertMatch.
+func WithCSVField(col int) Option {
+	return func(opts *Opts) {
+		opts.csvField = col
+	}
+}
+
+// WithSARIF replaces normal output with a SARIF 2.1.0 log, suitable for code
+// scanning services, with one rule per pattern and one result per match
+// locating it by input name, line and column. Columns count characters, not
+// bytes. The log is written once every input has been searched.
+func WithSARIF() Option {
+	return func(opts *Opts) {
+		opts.sarif = true
+	}
+}
+
+// WithSkipBinaryLines never selects lines that look binary, which declutters
+// output from text files with the occasional binary blob, such as some logs.
+// A line looks binary if it contains a NUL byte or if more than 30% of its
+// characters are non-printable; WithBinaryLineThreshold changes the latter.
+// Skipped lines are not selected even by WithInvertMatch.
+func WithSkipBinaryLines() Option {
+	return func(opts *Opts) {
+		opts.skipBinaryLines = true
+	}
+}
+
+// WithBinaryLineThreshold sets the share of non-printable characters, between
+// 0 and 1, above which WithSkipBinaryLines treats a line as binary.
+func WithBinaryLineThreshold(ratio float64) Option {
+	return func(opts *Opts) {
+		opts.binaryLineThreshold = ratio
+	}
+}
+
+// WithDiffFilter reads a unified diff, such as the output of git diff, and
+// only considers the lines it adds: any other line of a named input is never
+// selected, not even by WithInvertMatch. Inputs are paired with files in the
+// diff by name, so this is meant for searches over named inputs, e.g. to check
+// only the changed code for banned patterns in a pre-commit hook. Leading
+// "b/" prefixes in the diff are ignored. The diff is read when the search
+// starts.
+func WithDiffFilter(diff io.Reader) Option {
+	return func(opts *Opts) {
+		opts.diff = diff
+	}
+}
+
+// WithParagraphMode matches patterns against paragraphs rather than lines,
+// like awk with an empty RS. A paragraph is a run of non-empty lines; one or
+// more empty lines separate paragraphs. Selected paragraphs are printed whole,
+// separated by an empty line. Since a paragraph is matched as a single text,
+// ‘^’ and ‘$’ match at its start and end unless the (?m) flag is used.
+func WithParagraphMode() Option {
+	return func(opts *Opts) {
+		opts.paragraph = true
+	}
+}
+
+// WithPerLineCount prefixes each selected line with the number of matches in
+// it and a colon, e.g. "3:foo foo foo". Overlapping matches count once and,
+// with WithWordRegexp, only whole-word matches count. Lines selected by
+// WithInvertMatch have a count of 0.
+func WithPerLineCount() Option {
+	return func(opts *Opts) {
+		opts.perLineCount = true
+	}
+}
+
+// WithQuiet prints nothing at all, and stops the search at the first selected
+// line. Whether any line was selected can be learned with ExecWithResult, or
+// more directly with Matches or Check, which need no Option.
+func WithQuiet() Option {
+	return func(opts *Opts) {
+		opts.q = true
+	}
+}
+
+// WithNoMessages ignores files that cannot be opened or read, such as missing
+// ones or ones without permission, as grep -s does. Without it, the search
+// carries on past such files, but then fails with the first error.
+func WithNoMessages() Option {
+	return func(opts *Opts) {
+		opts.s = true
+	}
+}
+
+// WithBinaryFiles sets how inputs that contain a NUL byte, which grep takes
+// to be binary, are searched. mode is "binary" to print "Binary file NAME
+// matches" in place of the lines of such an input that has a selected line,
+// "text" to search it as any other input, or "without-match" to take it to
+// have no selected lines. Without this Option, binary inputs are searched as
+// text, except that recursive searches skip binary files. An unknown mode
+// makes the search fail.
+func WithBinaryFiles(mode string) Option {
+	return func(opts *Opts) {
+		opts.binaryFiles = mode
+	}
+}
+
+// WithText searches binary inputs as text, as grep -a does. It is
+// WithBinaryFiles("text").
+func WithText() Option {
+	return WithBinaryFiles("text")
+}
+
+// WithBinaryWithoutMatch takes binary inputs to have no selected lines, as
+// grep -I does. It is WithBinaryFiles("without-match").
+func WithBinaryWithoutMatch() Option {
+	return WithBinaryFiles("without-match")
+}
+
+// WithBinary keeps the carriage return that ends each line of CRLF input, as
+// grep -U does, rather than stripping it before the line is matched and
+// printed. Patterns anchored with $ then do not match before it.
+func WithBinary() Option {
+	return func(opts *Opts) {
+		opts.U = true
+	}
+}
+
+// WithDevices sets what Exec and recursive searches do with devices, FIFOs
+// and sockets, as grep -D does. action is "read" to search them as any other
+// file, or "skip" to leave them out. Without this Option, those named to
+// Exec are read and those found by walking a directory are skipped. An
+// unknown action makes the search fail.
+func WithDevices(action string) Option {
+	return func(opts *Opts) {
+		opts.devices = action
+	}
+}
+
+// WithRecursive has Exec search each directory it is given as ReadDir does,
+// and the working directory if it is given no files. Each line is then
+// prefixed with the path of its file, as if several had been given.
+func WithRecursive() Option {
+	return func(opts *Opts) {
+		opts.r = true
+	}
+}
+
+// WithDereferenceRecursive is WithRecursive, except that symbolic links to
+// files and directories are followed, both by Exec and by ReadDir. A link
+// back to a directory the walk is already in is not followed; the rest of the
+// tree is still searched, but the search then fails with an error naming it.
+func WithDereferenceRecursive() Option {
+	return func(opts *Opts) {
+		opts.r = true
+		opts.R = true
+	}
+}
+
+// WithInclude restricts recursive searches to files whose names match the
+// glob pattern, in the syntax of filepath.Match, as grep --include does. It
+// may be given more than once to allow several patterns.
+func WithInclude(pattern string) Option {
+	return func(opts *Opts) {
+		opts.include = append(opts.include, pattern)
+	}
+}
+
+// WithExclude makes recursive searches skip files whose names match the glob
+// pattern, as grep --exclude does. It takes precedence over WithInclude, and
+// may be given more than once.
+func WithExclude(pattern string) Option {
+	return func(opts *Opts) {
+		opts.exclude = append(opts.exclude, pattern)
+	}
+}
+
+// WithExcludeFrom reads glob patterns from file, one per line, and skips the
+// files they match as WithExclude does, as grep --exclude-from does. Blank
+// lines are ignored. If file cannot be read, the search fails with the error.
+func WithExcludeFrom(file *os.File) Option {
+	return func(opts *Opts) {
+		opts.excludeFrom = append(opts.excludeFrom, file)
+	}
+}
+
+// WithExcludeDir makes recursive searches skip directories whose names match
+// the glob pattern, and everything in them, as grep --exclude-dir does. It
+// may be given more than once.
+func WithExcludeDir(pattern string) Option {
+	return func(opts *Opts) {
+		opts.excludeDir = append(opts.excludeDir, pattern)
+	}
+}
+
+// WithFilesWithMatches prints only the names of the inputs with a selected
+// line, one per line, instead of their lines. Each input is only read up to
+// its first selected line.
+func WithFilesWithMatches() Option {
+	return func(opts *Opts) {
+		opts.l = true
+	}
+}
+
+// WithFilesWithoutMatch prints only the names of the inputs without a
+// selected line, one per line, instead of their lines. Each input is only
+// read up to its first selected line, if it has one.
+func WithFilesWithoutMatch() Option {
+	return func(opts *Opts) {
+		opts.L = true
+	}
+}
+
+// WithNullOutput ends each name printed by WithFilesWithMatches or
+// WithFilesWithoutMatch with a NUL rather than a newline, so that names
+// containing newlines survive, as for xargs -0. Other output is unaffected.
+func WithNullOutput() Option {
+	return func(opts *Opts) {
+		opts.Z = true
+	}
+}
+
+// WithInitialTab puts a tab between the prefix of each output line, such as
+// its file name or line number, and its text, as grep -T does, so that the
+// text starts on a tab stop and tabs within it line up from one line to the
+// next. Lines without a prefix or without text are unaffected.
+func WithInitialTab() Option {
+	return func(opts *Opts) {
+		opts.T = true
+	}
+}
+
+// WithFilesWithFirstMatch is like WithFilesWithMatches, but prints each
+// input's name followed by a colon and its first selected line, showing why it
+// matched. Each input is only read up to its first selected line.
+func WithFilesWithFirstMatch() Option {
+	return func(opts *Opts) {
+		opts.filesWithFirstMatch = true
+	}
+}
+
+// WithEntryStart matches patterns against multi-line entries rather than
+// lines. Each entry starts at a line matching the regular expression start,
+// e.g. a log line's leading timestamp, and runs up to the next such line, so
+// that a Java or Python stack trace stays with the log line it belongs to.
+// Selected entries are printed whole. Lines before the first start line make
+// up an entry of their own.
+func WithEntryStart(start string) Option {
+	return func(opts *Opts) {
+		opts.entryStart = start
+	}
+}
+
+// WithRecordRelativeOffset makes WithByteOffset report the offset of the first
+// match relative to the start of its record, rather than the offset of the
+// record from the start of input. This suits post-processing of
+// NUL-delimited records from WithNullData, where each consumer sees a record
+// on its own. The terminator of a record is not part of it, so it counts
+// towards the absolute offsets of later records but never towards a relative
+// one. Records selected by WithInvertMatch have a relative offset of 0.
+func WithRecordRelativeOffset() Option {
+	return func(opts *Opts) {
+		opts.recordRelativeOffset = true
+	}
+}
+
+// WithNotPrecededBy only accepts matches that are not immediately preceded
+// by a match of the regular expression expr, emulating the negative
+// lookbehind (?<!expr) that Go's regexp package lacks. For example, pattern
+// "bar" with WithNotPrecededBy("foo") matches "bar" but not "foobar". A line
+// is selected if any of its matches is accepted.
+func WithNotPrecededBy(expr string) Option {
+	return func(opts *Opts) {
+		opts.notPrecededBy = expr
+	}
+}
+
+// WithNotFollowedBy only accepts matches that are not immediately followed
+// by a match of the regular expression expr, emulating the negative lookahead
+// (?!expr). A line is selected if any of its matches is accepted.
+func WithNotFollowedBy(expr string) Option {
+	return func(opts *Opts) {
+		opts.notFollowedBy = expr
+	}
+}
+
+// WithCaret follows each selected line with a line of carets under its first
+// match, as compilers point at errors:
+//
+//	x := foo(bar)
+//	     ^^^
+//
+// Carets are aligned by display column: tabs before the match are repeated
+// and wide characters are padded to their width, so the carets line up in a
+// terminal. Lines selected without a match, as by WithInvertMatch, get no
+// caret line.
+func WithCaret() Option {
+	return func(opts *Opts) {
+		opts.caret = true
+	}
+}
+
+// WithAllCarets is like WithCaret but points at every match in the line, not
+// just the first.
+func WithAllCarets() Option {
+	return func(opts *Opts) {
+		opts.caret = true
+		opts.allCarets = true
+	}
+}
+
+// WithType restricts ReadDir to files of the named type, such as "go" for
+// files matching *.go, as ripgrep's --type does. It may be given more than
+// once to allow several types. Types other than the built-in ones are added
+// with RegisterType. Naming an unknown type makes ReadDir fail.
+func WithType(name string) Option {
+	return func(opts *Opts) {
+		opts.types = append(opts.types, name)
+	}
+}
+
+// WithTypeNot makes ReadDir skip files of the named type, as ripgrep's
+// --type-not does. It takes precedence over WithType.
+func WithTypeNot(name string) Option {
+	return func(opts *Opts) {
+		opts.typesNot = append(opts.typesNot, name)
+	}
+}
+
+// WithSummaryWriter writes a one-line summary of the search to w once every
+// input has been scanned, such as
+//
+//	matched=3 files=2 scanned_bytes=1024
+//
+// giving the number of lines selected, inputs read and bytes read. It is
+// written exactly once, even if nothing matched, but not if the search fails.
+// w is typically os.Stderr, keeping the summary apart from the output.
+func WithSummaryWriter(w io.Writer) Option {
+	return func(opts *Opts) {
+		opts.summary = w
+	}
+}
+
+// WithFirstMatchWins treats the patterns, from WithRegexps and then
+// WithFiles, as rules in order of priority and tags each selected line with
+// the 0-based index of the first rule that matches it. The index prefixes
+// each output line and is reported as Match.PatternIndex by Start. Lines
+// selected by WithInvertMatch match no pattern and are not tagged.
+func WithFirstMatchWins() Option {
+	return func(opts *Opts) {
+		opts.firstMatchWins = true
+	}
+}
+
+// WithPassthru prints every line of input, not just the selected ones, so
+// matches can be seen in place, as ripgrep's --passthru does. Lines that are
+// not selected have their prefixes, such as line numbers, separated by '-'
+// rather than ':', as GNU grep does for context lines, and are never
+// highlighted by WithColor.
+func WithPassthru() Option {
+	return func(opts *Opts) {
+		opts.passthru = true
+	}
+}
+
+// WithTreeOutput makes ReadDir, and Exec with WithRecursive, print instead
+// of the selected lines an overview of where they are: the directories and
+// files holding them as an indented tree, with the number of lines selected
+// in each file:
+//
+//	src/
+//	  main.go (2)
+//	  pkg/
+//	    lib.go (1)
+//
+// Files without selected lines are left out. Since the tree is sorted, it is
+// only printed once the whole tree has been searched; Exec prints one for each
+// directory it is given, and the lines of files named directly as usual. It
+// cannot be combined with -o or -c.
+func WithTreeOutput() Option {
+	return func(opts *Opts) {
+		opts.treeOutput = true
+	}
+}
+
+// WithOutputBufferSize buffers up to n bytes of output at a time, rather
+// than handing each line to the reader as it is found. This cuts the cost of
+// synchronizing with the reader when many lines are selected, at the price of
+// latency. Buffered output is flushed once the search ends, even if it fails,
+// and after every line with WithLineBuffered.
+func WithOutputBufferSize(n int) Option {
+	return func(opts *Opts) {
+		opts.outputBufferSize = n
+	}
+}
+
+// WithSample prints each selected line only with probability rate, giving a
+// random sample of roughly that fraction of the lines, which is enough to
+// survey a huge log. Output is therefore incomplete by design; counts such as
+// WithTotalOnly and WithSummaryWriter still cover every selected line. The
+// random numbers come from WithRandSource if set.
+func WithSample(rate float64) Option {
+	return func(opts *Opts) {
+		opts.sampled = true
+		opts.sampleRate = rate
+	}
+}
+
+// WithSampleN prints a random sample of exactly n of the selected lines, or
+// all of them if there are fewer, by reservoir sampling. Every selected line
+// is equally likely to be printed. The sample is held in memory and printed
+// in input order once every input has been scanned. Like WithSample, it only
+// affects which lines are printed.
+func WithSampleN(n int) Option {
+	return func(opts *Opts) {
+		opts.sampleN = n
+	}
+}
+
+// WithRandSource sets the source of the random numbers used by WithSample and
+// WithSampleN, so a seeded source gives a reproducible sample. By default
+// each run is seeded differently.
+func WithRandSource(src rand.Source) Option {
+	return func(opts *Opts) {
+		opts.randSource = src
+	}
+}
+
+// WithShowTrailingWhitespace makes trailing whitespace in output lines
+// visible, printing each trailing space as '·' and each trailing tab as '→',
+// which helps when hunting down whitespace problems. Whitespace elsewhere in
+// the line is printed as is. Only output is affected: patterns are matched
+// against the line as read, and WithColor highlights matches that include
+// trailing whitespace with the markers in place of it.
+func WithShowTrailingWhitespace() Option {
+	return func(opts *Opts) {
+		opts.showTrailingWhitespace = true
+	}
+}
+
+// WithAllPatterns selects only lines matched by every pattern, rather than by
+// any, so that WithRegexps("X", "Y") does in one pass what grep X | grep Y
+// does in two.
+func WithAllPatterns() Option {
+	return func(opts *Opts) {
+		opts.allPatterns = true
+	}
+}
+
+// WithNonePatterns selects only lines matched by none of the patterns. With
+// a single pattern it is the same as WithInvertMatch.
+func WithNonePatterns() Option {
+	return func(opts *Opts) {
+		opts.nonePatterns = true
+	}
+}
+
+// WithSuffixSearch decides whether a line matches by matching the reverse of
+// each pattern against the reversed line. A pattern anchored at the end of
+// the line, such as `status=\d+$`, then becomes anchored at the start, so a
+// non-matching line is rejected by looking at its last few characters rather
+// than scanning it whole, which pays off on long lines with a fixed-format
+// suffix. The lines selected are the same as without it, and are printed as
+// read.
+func WithSuffixSearch() Option {
+	return func(opts *Opts) {
+		opts.suffixSearch = true
+	}
+}
+
+// WithMergeContext prints overlapping or adjacent context as one block, with
+// "--" only between blocks that are apart. This is how the context set by
+// WithBeforeContext, WithAfterContext or WithContext is always printed, so
+// WithMergeContext changes nothing; it is kept for searches written before
+// merging became the default.
+func WithMergeContext() Option {
+	return func(opts *Opts) {}
+}
+
+// WithMaxPerFile stops reading each input after its first n selected lines,
+// and any trailing context they have, then moves on to the next. Unlike
+// WithMaxCount, which stops the whole search, this keeps one noisy file among
+// many from crowding out the rest of the output.
+func WithMaxPerFile(n int) Option {
+	return func(opts *Opts) {
+		opts.maxPerFile = n
+	}
+}
+
+// WithReplacePreview previews a sed-like substitution: instead of each
+// selected line, it prints the line as it is, prefixed with '-', and as it
+// would be with every match replaced by template, prefixed with '+'. Within
+// template, $1 or ${name} stands for the text of a capture group, as in
+// regexp.Expand. With several patterns, each one's replacements are applied in
+// turn. Lines without a match, as selected by WithInvertMatch, are left out.
+func WithReplacePreview(template string) Option {
+	return func(opts *Opts) {
+		opts.replacePreview = true
+		opts.replaceTemplate = template
+	}
+}
+
+// WithDimContext renders context lines, such as those printed by
+// WithContext or WithPassthru, in the ANSI dim attribute when WithColor
+// is highlighting, so that selected lines and their matches stand out. It
+// only changes how lines are displayed.
+func WithDimContext() Option {
+	return func(opts *Opts) {
+		opts.dimContext = true
+	}
+}
+
+// WithPreserveEOL prints each line with the terminator it had in the input,
+// so that CRLF line endings survive and a last line without a newline is
+// printed without one, instead of ending every line with a newline (or a NUL,
+// with WithNullData). Only lines are affected; anything else printed keeps
+// the usual terminator.
+func WithPreserveEOL() Option {
+	return func(opts *Opts) {
+		opts.preserveEOL = true
+	}
+}
+
+// WithSpillContext bounds the memory held for the leading context set by
+// WithBeforeContext or WithContext, by moving it to a temporary file in tmpDir
+// once it exceeds a threshold (1MiB unless WithSpillThreshold says otherwise).
+// This allows a large amount of leading context on huge inputs. If tmpDir is
+// empty, the default directory for temporary files is used. The file is
+// removed when the search ends, even if it fails.
+func WithSpillContext(tmpDir string) Option {
+	return func(opts *Opts) {
+		opts.spillContext = true
+		opts.spillDir = tmpDir
+	}
+}
+
+// WithSpillThreshold sets how many bytes of leading context WithSpillContext
+// holds in memory before moving it to disk.
+func WithSpillThreshold(n int) Option {
+	return func(opts *Opts) {
+		opts.spillThreshold = n
+	}
+}
+
+// WithMaxLineLength sets the length in bytes, counting its terminator, of the
+// longest record that can be read, for input such as minified JSON with very
+// long lines. A longer record ends the search with bufio.ErrTooLong. 0 means
+// there is no limit, with the buffer growing as needed; the default is
+// bufio.MaxScanTokenSize, 64KiB.
+func WithMaxLineLength(n int) Option {
+	return func(opts *Opts) {
+		opts.maxLineLength = n
+	}
+}
+
+// WithNonMatching prints, for each selected line, only the parts of the line
+// that lie between matches. It is the complement of -o: where -o shows what
+// matched, this shows what remains once the matches are cut out. The
+// remaining segments are joined with the separator set by
+// WithNonMatchingSeparator, which defaults to the empty string.
+func WithNonMatching() Option {
+	return func(opts *Opts) {
+		opts.nonMatching = true
+	}
+}
+
+// WithNonMatchingSeparator sets the string used to join the segments printed
+// by WithNonMatching. It has no effect unless WithNonMatching is also set.
+func WithNonMatchingSeparator(sep string) Option {
+	return func(opts *Opts) {
+		opts.nonMatchingSep = sep
+	}
+}
+
+// setSyntax sets the syntax of patterns for the Option named name. Later
+// Options win, but fixed strings and basic regexps conflict, whatever came
+// between them.
+func (opts *Opts) setSyntax(syntax patternSyntax, name string) {
+	opts.syntax = syntax
+	if syntax != fixedStrings && syntax != basicRegexp {
+		return
+	}
+	if opts.fixedOrBasic == "" {
+		opts.fixedOrBasic = name
+	} else if opts.fixedOrBasic != name && opts.conflict == nil {
+		opts.conflict = &OptionConflictError{Options: [2]string{opts.fixedOrBasic, name}}
+	}
+}
+
+// patternSyntax is how patterns are interpreted.
+type patternSyntax int
+
+const (
+	perlRegexp patternSyntax = iota
+	fixedStrings
+	basicRegexp
+	extendedRegexp
+)
+
 type Opts struct {
 	// Matching Control
 	// https://www.gnu.org/software/grep/manual/grep.html#Matching-Control
 
+	//   -E, --extended-regexp     PATTERN is an extended regular expression
+	//   -F, --fixed-strings       PATTERN is a set of newline-separated strings
+	//   -G, --basic-regexp        PATTERN is a basic regular expression
+	//   -P, --perl-regexp         PATTERN is a Perl regular expression (default)
+	syntax patternSyntax
+	// the first of WithFixedStrings and WithBasicRegexp applied, and the
+	// conflict of the other with it
+	fixedOrBasic string
+	conflict     *OptionConflictError
 	//   -e, --regexp=PATTERN      use PATTERN for matching
 	e []string
 	//   -f, --file=FILE           obtain PATTERN from FILE
@@ -102,7 +975,7 @@ type Opts struct {
 	z bool
 
 	//   -m, --max-count=NUM       stop after NUM selected lines
-	m int
+	m int // no limit if negative
 	//   -b, --byte-offset         print the byte offset with output lines
 	b bool
 	//   -n, --line-number         print line number with output lines
@@ -118,179 +991,1204 @@ type Opts struct {
 	//   -o, --only-matching       show only the part of a line matching PATTERN
 	o bool
 	//   -q, --quiet, --silent     suppress all normal output
+	q bool
+	//   -s, --no-messages         suppress error messages
+	s bool
 	//       --binary-files=TYPE   assume that binary files are TYPE;
 	//                             TYPE is 'binary', 'text', or 'without-match'
 	//   -a, --text                equivalent to --binary-files=text
 	//   -I                        equivalent to --binary-files=without-match
+	binaryFiles string
 	//   -d, --directories=ACTION  how to handle directories;
 	//                             ACTION is 'read', 'recurse', or 'skip'
 	//   -D, --devices=ACTION      how to handle devices, FIFOs and sockets;
 	//                             ACTION is 'read' or 'skip'
+	devices string
 	//   -r, --recursive           like --directories=recurse
+	r bool
 	//   -R, --dereference-recursive  likewise, but follow all symlinks
+	R bool
 	//       --include=FILE_PATTERN  search only files that match FILE_PATTERN
+	include []string
 	//       --exclude=FILE_PATTERN  skip files and directories matching FILE_PATTERN
+	exclude []string
 	//       --exclude-from=FILE   skip files matching any file pattern from FILE
+	excludeFrom []*os.File
 	//       --exclude-dir=PATTERN  directories that match PATTERN will be skipped.
+	excludeDir []string
 	//   -L, --files-without-match  print only names of FILEs with no selected lines
+	L bool
 	//   -l, --files-with-matches  print only names of FILEs with selected lines
+	l bool
 	//   -c, --count               print only a count of selected lines per FILE
+	c bool
 	//   -T, --initial-tab         make tabs line up (if needed)
+	T bool
 	//   -Z, --null                print 0 byte after FILE name
+	Z bool
+
+	// Context control:
+	//   -B, --before-context=NUM  print NUM lines of leading context
+	B int
+	//   -A, --after-context=NUM   print NUM lines of trailing context
+	A int
+	//   -C, --context=NUM         print NUM lines of output context
+	//   -NUM                      same as --context=NUM
+	//       --color[=WHEN],
+	//       --colour[=WHEN]       use markers to highlight the matching strings;
+	//                             WHEN is 'always', 'never', or 'auto'
+	colorWhen string
+	isTTY     bool
+	// whether to highlight, as decided by colorWhen and isTTY
+	color bool
+	//   -U, --binary              do not strip CR characters at EOL (MSDOS/Windows)
+	U bool
+
+	// Extensions
+	// These have no GNU grep equivalent.
+
+	// reject lines with a bloom filter before matching
+	bloom bool
+	// test each pattern on its own rather than all at once; set by tests
+	noCombine bool
+	// match patterns approximately, within a number of edits
+	fuzzy    bool
+	maxEdits int
+	// search only files of these types, and never of these
+	types    []string
+	typesNot []string
+	// print a random sample of the selected lines
+	sampled    bool
+	sampleRate float64
+	sampleN    int
+	randSource rand.Source
+	// buffer this many bytes of output
+	outputBufferSize int
+	// print where the selected lines are as a directory tree
+	treeOutput bool
+	// print trailing whitespace visibly
+	showTrailingWhitespace bool
+	// stop reading each input after this many selected lines
+	maxPerFile int
+	// print lines before and after replacing matches
+	replacePreview  bool
+	replaceTemplate string
+	// dim context lines when highlighting
+	dimContext bool
+	// end lines as they ended in the input
+	preserveEOL bool
+	// the longest record that can be read, or 0 for no limit
+	maxLineLength int
+	// hold leading context beyond spillThreshold bytes in a file in spillDir
+	spillContext   bool
+	spillDir       string
+	spillThreshold int
+	// print unselected lines too
+	passthru bool
+	// combine patterns with AND, or NOR, rather than OR
+	allPatterns  bool
+	nonePatterns bool
+	// tag lines with the first pattern that matches them
+	firstMatchWins bool
+	// write a summary of the search here
+	summary io.Writer
+	// point at matches with carets
+	caret     bool
+	allCarets bool
+	// match reversed patterns against reversed lines
+	suffixSearch bool
+	// reject matches by what surrounds them
+	notPrecededBy string
+	notFollowedBy string
+	// match paragraphs instead of lines
+	paragraph bool
+	// match multi-line entries beginning with this pattern instead of lines
+	entryStart string
+	// print only the count of lines selected across all inputs
+	totalOnly bool
+	// match against the value at a JSON key path
+	jsonPath string
+	// match against the values of logfmt keys
+	logfmtKeys []string
+	// match against a CSV field, counting from 1
+	csvField int
+	// group lines by the text of a capture group
+	sectioned bool
+	sectionBy int
+	// never select lines that look binary
+	skipBinaryLines     bool
+	binaryLineThreshold float64
+	// only consider lines added by a diff
+	diff io.Reader
+	// print the name and first selected line of each input
+	filesWithFirstMatch bool
+	// report byte offsets relative to the start of the record
+	recordRelativeOffset bool
+	// prefix lines with their number of matches
+	perLineCount bool
+	// print matches as a SARIF log
+	sarif bool
+	// print only the text between matches
+	nonMatching    bool
+	nonMatchingSep string
+
+	// Programs:
+	// https://www.gnu.org/software/grep/manual/grep.html#grep-Programs
+}
+
+// listsInputs reports whether lines are never printed, only something about
+// each input, if anything.
+func (opts *Opts) listsInputs() bool {
+	return opts.c || opts.l || opts.L || opts.q
+}
+
+// Grep searches input files for matches to patterns. When it finds a match in
+// a line, it copies the line to the output.
+//
+// Though Grep expects to do the matching on text, it has no limits on input
+// line length other than available memory, and it can match arbitrary
+// characters within a line. If the final byte of an input file is not a
+// newline, grep silently supplies one, so the last line is matched and printed
+// like any other, newline and all, unless WithPreserveEOL is set. Since
+// newline is also a separator for the list of patterns, there is no way to
+// match newline characters in a text.
+type Grep struct {
+	pattern string
+	opts    *Opts
+
+	// read by readOnce from WithFiles and WithDiffFilter
+	read         sync.Once
+	filePatterns []string
+	added        addedLines
+	readErr      error
+}
+
+// New returns a Grep that matches pattern with opts set. The pattern argument
+// contains one or more patterns separated by newlines. Each resulting pattern is
+// interpreted according to the regexp package. Patterns given by WithRegexps
+// and WithFiles are searched for as well as pattern, unlike GNU grep, which
+// takes its first operand as a file when -e or -f is given; pass "" to search
+// for theirs alone.
+func New(pattern string, opts ...Option) *Grep {
+	Opts := &Opts{
+		binaryLineThreshold: defaultBinaryLineThreshold,
+		maxLineLength:       bufio.MaxScanTokenSize,
+		m:                   -1,
+	}
+	for _, opt := range opts {
+		opt(Opts)
+	}
+	Opts.color = Opts.colorWhen == "always" || Opts.colorWhen == "auto" && Opts.isTTY
+	return &Grep{
+		pattern: pattern,
+		opts:    Opts,
+	}
+}
+
+// Exec searches the files named by args in turn, as grep does, and returns the
+// combined output. "-" names the standard input, which is searched when args
+// is empty. Files are opened as the search reaches them; if one cannot be
+// opened, the search carries on with the rest, then reading the output fails
+// with the error.
+func (cmd *Grep) Exec(args []string) io.Reader {
+	if len(args) == 0 && cmd.opts.r {
+		args = []string{"."}
+	} else if len(args) == 0 {
+		args = []string{"-"}
+	}
+	r, w := newPipe()
+
+	matcher, err := cmd.allMatcher()
+	if err != nil {
+		w.CloseWithError(err)
+		return r
+	}
+	types, err := newTypeFilter(cmd.opts)
+	if err != nil {
+		w.CloseWithError(err)
+		return r
+	}
+
+	go func() {
+		run := cmd.newRun(matcher, w)
+		run.names = !cmd.opts.h && (cmd.opts.H || cmd.opts.r || len(args) > 1)
+		run.close(w, run.scanArgs(args, types))
+	}()
+
+	return r
+}
+
+// scanArgs scans each of the files named by args in turn, walking those that
+// are directories with WithRecursive and skipping devices as WithDevices says,
+// then finishes the run.
+func (run *run) scanArgs(args []string, types *typeFilter) error {
+	for _, arg := range args {
+		if run.stopped() {
+			break
+		}
+		var err error
+		if arg == "-" {
+			err = run.scan(NamedReader{Reader: os.Stdin})
+		} else if info, statErr := os.Stat(arg); statErr != nil {
+			err = run.scanFile(arg)
+		} else if info.IsDir() && run.cmd.opts.r && run.cmd.opts.treeOutput {
+			run.tree = newTree(arg)
+			if err = run.scanTree(arg, types); err == nil {
+				err = run.tree.write(run.w)
+			}
+			run.tree = nil
+		} else if info.IsDir() && run.cmd.opts.r {
+			err = run.scanTree(arg, types)
+		} else if !isDevice(info.Mode()) || run.cmd.opts.devices != "skip" {
+			err = run.scanFile(arg)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return run.finish()
+}
+
+func (cmd *Grep) Read(input io.Reader) io.Reader {
+	return cmd.ReadNamed(NamedReader{Reader: input})
+}
+
+// NamedReader is an input along with the name it is known by, typically the
+// name of the file it was opened from.
+type NamedReader struct {
+	Name string
+	io.Reader
+}
+
+// name returns the name input is printed under, which is label if input has
+// no name of its own and label is not empty.
+func (input NamedReader) name(label string) string {
+	switch {
+	case input.Name != "":
+		return input.Name
+	case label != "":
+		return label
+	}
+	return "(standard input)"
+}
+
+// ReadNamed searches each of inputs in turn, as grep does when given several
+// files, and returns the combined output. As with grep, lines are prefixed
+// with the name of their input when there is more than one input, or when
+// WithFilename is set, unless WithNoFilename is.
+func (cmd *Grep) ReadNamed(inputs ...NamedReader) io.Reader {
+	return cmd.readNamed(nil, inputs...)
+}
+
+// readNamed is ReadNamed, calling done, if not nil, with the number of lines
+// selected and any error once the search ends.
+func (cmd *Grep) readNamed(done func(selected int, err error), inputs ...NamedReader) io.Reader {
+	r, w := newPipe()
+	if done == nil {
+		done = func(int, error) {}
+	}
+
+	matcher, err := cmd.allMatcher()
+	if err != nil {
+		w.CloseWithError(err)
+		done(0, err)
+		return r
+	}
+
+	go func() {
+		run := cmd.newRun(matcher, w)
+		run.names = !cmd.opts.h && (cmd.opts.H || len(inputs) > 1)
+		err := run.scanAll(inputs)
+		run.close(w, err)
+		done(run.selected, err)
+	}()
+
+	return r
+}
+
+// scanAll scans each of inputs in turn, then finishes the run.
+func (run *run) scanAll(inputs []NamedReader) error {
+	for _, input := range inputs {
+		if run.stopped() {
+			break
+		}
+		if err := run.scan(input); err != nil {
+			return err
+		}
+	}
+	return run.finish()
+}
+
+// run holds the state of one search over one or more inputs.
+type run struct {
+	cmd     *Grep
+	matcher *matchAll
+	w       io.Writer
+	// buffers w, if WithOutputBufferSize is set
+	bw *bufio.Writer
+
+	// prefix lines with the name of their input
+	names bool
+	// lines selected, inputs opened and bytes read across all inputs
+	selected int
+	files    int
+	scanned  int64
+	// records written, for separating paragraphs
+	written int
+	// the output line being built
+	buf []byte
+	// terminator of the line being printed, if WithPreserveEOL is set
+	eol      []byte
+	sections *sections
+	sarif    *sarifLog
+	tree     *tree
+	sampler  *sampler
+	context  *contextPrinter
+	// the first error the search carried on past, returned once it ends
+	skipped error
+}
+
+func (cmd *Grep) newRun(matcher *matchAll, w io.Writer) *run {
+	run := &run{cmd: cmd, matcher: matcher, w: w}
+	if cmd.opts.outputBufferSize > 0 {
+		run.bw = bufio.NewWriterSize(w, cmd.opts.outputBufferSize)
+		run.w = run.bw
+	}
+	if cmd.opts.sectioned {
+		run.sections = newSections()
+	}
+	run.sampler = newSampler(cmd.opts)
+	run.context = newContextPrinter(cmd.opts)
+	if cmd.opts.sarif {
+		run.sarif = newSARIF(matcher)
+	}
+	return run
+}
+
+// close flushes any buffered output and removes any context spilled to disk,
+// even if the search failed, then closes w with err.
+func (run *run) close(w *pipeWriter, err error) {
+	if run.bw != nil {
+		if flushErr := run.bw.Flush(); err == nil {
+			err = flushErr
+		}
+	}
+	if run.context != nil {
+		if closeErr := run.context.close(); err == nil {
+			err = closeErr
+		}
+	}
+	w.CloseWithError(err)
+}
+
+// scan writes the lines of input selected by the matcher.
+func (run *run) scan(input NamedReader) error {
+	opts := run.cmd.opts
+	run.files++
+	input.Reader = &countingReader{Reader: input.Reader, n: &run.scanned}
+	s := run.matcher.newScanner(input)
+	if run.context != nil {
+		if err := run.context.reset(); err != nil {
+			return err
+		}
+	}
+
+	// lines selected in this input
+	var selected int
+	capped := func() bool {
+		return opts.maxPerFile > 0 && selected >= opts.maxPerFile || run.stopped()
+	}
+	done := func() bool {
+		if (opts.l || opts.L) && selected > 0 {
+			// whether input has a selected line is all there is to know
+			return true
+		}
+		return capped() && (run.context == nil || run.context.after == 0)
+	}
+
+	var lineNo int
+	for !done() && s.Scan() {
+		if s.binary && opts.binaryFiles == "without-match" {
+			break
+		}
+		lineNo++
+		line := s.Bytes()
+		run.eol = s.eol
+		if capped() {
+			// only the trailing context of the last selected line remains
+			if err := run.asContext(input, lineNo, s.offset, line); err != nil {
+				return err
+			}
+			continue
+		}
+		if run.matcher.added != nil && !run.matcher.added.has(input.Name, lineNo) {
+			continue
+		}
+		if !run.matcher.Match(line) {
+			if run.context != nil {
+				if err := run.asContext(input, lineNo, s.offset, line); err != nil {
+					return err
+				}
+				continue
+			}
+			if opts.passthru && !opts.listsInputs() {
+				if err := run.print(input, lineNo, s.offset, line, '-'); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		run.selected++
+		selected++
+		switch {
+		case opts.totalOnly, opts.listsInputs():
+			continue
+		case run.sarif != nil:
+			run.sarif.add(run.matcher, input.Name, lineNo, line)
+			continue
+		case run.sections != nil:
+			run.sections.add(run.matcher.submatch(line, opts.sectionBy), line)
+			continue
+		case run.tree != nil:
+			run.tree.add(input.Name)
+			continue
+		case opts.replacePreview:
+			if err := run.preview(line); err != nil {
+				return err
+			}
+			continue
+		}
+		if s.binary && opts.binaryFiles == "binary" {
+			return run.binaryMatches(input)
+		}
+		if run.sampler != nil {
+			if !run.sampler.keep() {
+				continue
+			}
+			if run.sampler.n > 0 {
+				run.sampler.add(input, lineNo, s.offset, line, s.eol)
+				continue
+			}
+		}
+		if run.context != nil {
+			if err := run.withContext(input, lineNo, s.offset, line); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := run.print(input, lineNo, s.offset, line, ':'); err != nil {
+			return err
+		}
+		if opts.filesWithFirstMatch {
+			return nil
+		}
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+	switch {
+	case opts.q:
+	case opts.c:
+		return run.count(input, selected)
+	case opts.l && selected > 0, opts.L && selected == 0:
+		return run.printName(input)
+	}
+	return nil
+}
 
-	// Context control:
-	//   -B, --before-context=NUM  print NUM lines of leading context
-	//   -A, --after-context=NUM   print NUM lines of trailing context
-	//   -C, --context=NUM         print NUM lines of output context
-	//   -NUM                      same as --context=NUM
-	//       --color[=WHEN],
-	//       --colour[=WHEN]       use markers to highlight the matching strings;
-	//                             WHEN is 'always', 'never', or 'auto'
-	//   -U, --binary              do not strip CR characters at EOL (MSDOS/Windows)
+// printName writes the name of input on a line of its own, or followed by a
+// NUL with WithNullOutput.
+func (run *run) printName(input NamedReader) error {
+	run.buf = append(run.buf[:0], input.name(run.cmd.opts.label)...)
+	if run.cmd.opts.Z {
+		run.buf = append(run.buf, 0)
+	} else {
+		run.buf = append(run.buf, '\n')
+	}
+	if _, err := run.w.Write(run.buf); err != nil {
+		return err
+	}
+	return run.lineWritten()
+}
 
-	// Programs:
-	// https://www.gnu.org/software/grep/manual/grep.html#grep-Programs
+// binaryMatches writes that input, which is binary, has a selected line.
+func (run *run) binaryMatches(input NamedReader) error {
+	run.buf = append(run.buf[:0], "Binary file "...)
+	run.buf = append(run.buf, input.name(run.cmd.opts.label)...)
+	run.buf = append(run.buf, " matches\n"...)
+	if _, err := run.w.Write(run.buf); err != nil {
+		return err
+	}
+	return run.lineWritten()
 }
 
-// Grep searches input files for matches to patterns. When it finds a match in
-// a line, it copies the line to the output.
-//
-// Though Grep expects to do the matching on text, it has no limits on input
-// line length other than available memory, and it can match arbitrary
-// characters within a line. If the final byte of an input file is not a
-// newline, grep silently supplies one. Since newline is also a separator for
-// the list of patterns, there is no way to match newline characters in a text.
-type Grep struct {
-	pattern string
-	opts    *Opts
+// count writes the number of lines selected in input.
+func (run *run) count(input NamedReader, selected int) error {
+	run.buf = run.buf[:0]
+	if run.names {
+		run.buf = append(run.buf, input.name(run.cmd.opts.label)...)
+		run.buf = append(run.buf, ':')
+	}
+	run.buf = strconv.AppendInt(run.buf, int64(selected), 10)
+	run.buf = append(run.buf, '\n')
+	if _, err := run.w.Write(run.buf); err != nil {
+		return err
+	}
+	return run.lineWritten()
 }
 
-// New returns a Grep that matches pattern with opts set. The pattern argument
-// contains one or more patterns separated by newlines. Each resulting pattern is
-// interpreted according to the regexp package.
-func New(opts ...Opt) *Grep {
-	Opts := &Opts{}
-	for _, opt := range opts {
-		opt(Opts)
+// stopped reports whether the search has selected as many lines as
+// WithMaxCount allows, or any line with WithQuiet.
+func (run *run) stopped() bool {
+	opts := run.cmd.opts
+	return opts.m >= 0 && run.selected >= opts.m || opts.q && run.selected > 0
+}
+
+// countingReader adds the number of bytes read to n.
+type countingReader struct {
+	io.Reader
+	n *int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	*r.n += int64(n)
+	return n, err
+}
+
+// print writes line lineNo of input, found at offset, along with any
+// prefixes. Prefixes end in sep, which is ':' for selected lines and '-' for
+// others.
+func (run *run) print(input NamedReader, lineNo int, offset int64, line []byte, sep byte) error {
+	opts := run.cmd.opts
+
+	if opts.paragraph && run.written > 0 {
+		if _, err := io.WriteString(run.w, "\n"); err != nil {
+			return err
+		}
 	}
-	return &Grep{
-		opts: Opts,
+
+	if opts.o {
+		return run.printMatches(input, lineNo, offset, line, sep)
+	}
+	if opts.b && opts.recordRelativeOffset {
+		offset = 0
+		if i := run.matcher.indexes(line); len(i) > 0 {
+			offset = int64(i[0][0])
+		}
+	}
+	run.buf = run.appendPrefix(run.buf[:0], input, lineNo, offset, line, sep)
+	if opts.nonMatching {
+		line = run.matcher.nonMatching(line, opts.nonMatchingSep)
+	}
+	prefix := len(run.buf)
+	if opts.color && opts.dimContext && sep == '-' {
+		run.buf = append(run.buf, colorDim...)
+		run.buf = appendDisplayed(run.buf, line, nil, opts.showTrailingWhitespace)
+		run.buf = append(run.buf, colorReset...)
+	} else {
+		var spans [][]int
+		if opts.color && !opts.nonMatching {
+			spans = run.matcher.indexes(line)
+		}
+		run.buf = appendDisplayed(run.buf, line, spans, opts.showTrailingWhitespace)
+	}
+	if opts.preserveEOL {
+		run.buf = append(run.buf, run.eol...)
+	} else {
+		run.buf = run.terminate(run.buf)
 	}
+
+	if opts.caret && !opts.nonMatching {
+		if spans := run.matcher.indexes(line); len(spans) > 0 {
+			if !opts.allCarets {
+				spans = spans[:1]
+			}
+			run.buf = appendCarets(run.buf, run.buf[:prefix], line, spans)
+			run.buf = run.terminate(run.buf)
+		}
+	}
+
+	if _, err := run.w.Write(run.buf); err != nil {
+		return err
+	}
+	run.written++
+	return run.lineWritten()
 }
 
-func (cmd *Grep) Exec(args []string) io.Reader {
-	panic("todo")
+// printMatches writes each non-empty match in line on a line of its own, with
+// the prefix print would give line, as -o does. Lines of context have no
+// matches to show.
+func (run *run) printMatches(input NamedReader, lineNo int, offset int64, line []byte, sep byte) error {
+	opts := run.cmd.opts
+	if sep != ':' {
+		return nil
+	}
+	for _, span := range run.matcher.indexes(line) {
+		if span[0] == span[1] {
+			continue
+		}
+		matchOffset := offset + int64(span[0])
+		if opts.recordRelativeOffset {
+			matchOffset = int64(span[0])
+		}
+		run.buf = run.appendPrefix(run.buf[:0], input, lineNo, matchOffset, line, sep)
+		var spans [][]int
+		if opts.color {
+			spans = [][]int{{0, span[1] - span[0]}}
+		}
+		run.buf = appendDisplayed(run.buf, line[span[0]:span[1]], spans, opts.showTrailingWhitespace)
+		run.buf = run.terminate(run.buf)
+		if _, err := run.w.Write(run.buf); err != nil {
+			return err
+		}
+		run.written++
+		if err := run.lineWritten(); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (cmd *Grep) Read(input io.Reader) io.Reader {
-	r, w := io.Pipe()
+// lineWritten flushes buffered output once a line has been written, if
+// WithLineBuffered asks for each line to be readable as soon as it is found.
+func (run *run) lineWritten() error {
+	if run.bw != nil && run.cmd.opts.lineBuffered {
+		return run.bw.Flush()
+	}
+	return nil
+}
 
-	matcher, err := cmd.allMatcher()
-	if err != nil {
-		w.CloseWithError(err)
-		return r
+// appendPrefix appends to buf the fields print writes before line, each
+// followed by sep, and then a tab with WithInitialTab.
+func (run *run) appendPrefix(buf []byte, input NamedReader, lineNo int, offset int64, line []byte, sep byte) []byte {
+	opts := run.cmd.opts
+	start := len(buf)
+	if opts.filesWithFirstMatch || run.names {
+		buf = append(buf, input.name(run.cmd.opts.label)...)
+		buf = append(buf, sep)
 	}
+	if opts.n {
+		buf = strconv.AppendInt(buf, int64(lineNo), 10)
+		buf = append(buf, sep)
+	}
+	if opts.b {
+		buf = strconv.AppendInt(buf, offset, 10)
+		buf = append(buf, sep)
+	}
+	if opts.perLineCount {
+		buf = strconv.AppendInt(buf, int64(len(run.matcher.indexes(line))), 10)
+		buf = append(buf, sep)
+	}
+	if opts.firstMatchWins {
+		if i := run.matcher.patternIndex(line); i >= 0 {
+			buf = strconv.AppendInt(buf, int64(i), 10)
+			buf = append(buf, sep)
+		}
+	}
+	if opts.T && len(buf) > start && len(line) > 0 {
+		buf = append(buf, '\t')
+	}
+	return buf
+}
 
-	go func() {
-		s := bufio.NewScanner(input)
-
-		for s.Scan() {
-			line := s.Bytes()
-			if matcher.Match(line) {
-				_, err := w.Write(append(line, '\n'))
-				if err != nil {
-					w.CloseWithError(err)
-					return
-				}
+// terminate appends the output line terminator to buf.
+func (run *run) terminate(buf []byte) []byte {
+	if run.cmd.opts.z {
+		return append(buf, 0)
+	}
+	return append(buf, '\n')
+}
+
+// finish writes any output held back until every input has been scanned,
+// then the summary if one was asked for.
+func (run *run) finish() error {
+	if run.sampler != nil && run.sampler.n > 0 {
+		for _, l := range run.sampler.lines() {
+			run.eol = l.eol
+			if err := run.print(l.input, l.lineNo, l.offset, l.line, ':'); err != nil {
+				return err
 			}
 		}
+	}
+	if err := run.flush(); err != nil {
+		return err
+	}
+	if run.cmd.opts.summary != nil {
+		if _, err := fmt.Fprintf(run.cmd.opts.summary, "matched=%d files=%d scanned_bytes=%d\n", run.selected, run.files, run.scanned); err != nil {
+			return err
+		}
+	}
+	return run.skipped
+}
 
-		w.CloseWithError(s.Err())
-	}()
+// skip records err, an error the search carries on past, so that it is
+// returned once the search ends.
+func (run *run) skip(err error) {
+	if run.skipped == nil {
+		run.skipped = err
+	}
+}
 
-	return r
+// fileError carries on past err, an error opening or reading a file, unless
+// WithNoMessages is set, in which case it is ignored.
+func (run *run) fileError(err error) error {
+	if !run.cmd.opts.s {
+		run.skip(err)
+	}
+	return nil
+}
+
+// flush writes any output held back until every input has been scanned.
+func (run *run) flush() error {
+	switch {
+	case run.cmd.opts.q:
+	case run.cmd.opts.totalOnly:
+		_, err := fmt.Fprintln(run.w, run.selected)
+		return err
+	case run.sections != nil:
+		return run.sections.write(run.w)
+	case run.sarif != nil:
+		return run.sarif.write(run.w)
+	case run.tree != nil:
+		return run.tree.write(run.w)
+	}
+	return nil
+}
+
+// sections groups lines under a header, in order of each header's first
+// appearance.
+type sections struct {
+	headers []string
+	lines   map[string][][]byte
+}
+
+func newSections() *sections {
+	return &sections{lines: map[string][][]byte{}}
+}
+
+func (ss *sections) add(header string, line []byte) {
+	if _, ok := ss.lines[header]; !ok {
+		ss.headers = append(ss.headers, header)
+	}
+	ss.lines[header] = append(ss.lines[header], append([]byte(nil), line...))
+}
+
+func (ss *sections) write(w io.Writer) error {
+	for _, header := range ss.headers {
+		if _, err := io.WriteString(w, "== "+header+" ==\n"); err != nil {
+			return err
+		}
+		for _, line := range ss.lines[header] {
+			if _, err := w.Write(append(line, '\n')); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
 
 type matcher struct {
 	regexp *regexp.Regexp
-	opts   *Opts
+	// reject matches preceded or followed by these
+	notPrecededBy *regexp.Regexp
+	notFollowedBy *regexp.Regexp
+	// the reverse of regexp, to match reversed lines against
+	reversed *regexp.Regexp
+	// tested as one of the alternatives in matchAll.combined
+	combined bool
+	buf      []byte
+	opts     *Opts
 }
 
 func (m *matcher) match(line []byte) bool {
-	if !m.regexp.Match(line) {
+	if m.reversed != nil {
+		m.buf = appendReversed(m.buf[:0], line)
+		if !m.reversed.Match(m.buf) {
+			return false
+		}
+	} else if !m.regexp.Match(line) {
 		return false
 	}
+	if m.opts.x || m.opts.w || m.notPrecededBy != nil || m.notFollowedBy != nil {
+		return len(m.indexes(line)) > 0
+	}
+	return true
+}
+
+// indexes returns the start and end of every match in line that satisfies
+// the line, word and lookaround constraints.
+func (m *matcher) indexes(line []byte) [][]int {
+	var indexes [][]int
 
 	// match lines only
 	if m.opts.x {
 		match := m.regexp.Find(line)
+		equal := bytes.Equal(match, line)
 		if m.opts.i {
-			return bytes.EqualFold(match, line)
+			equal = bytes.EqualFold(match, line)
+		}
+		if !equal {
+			return nil
 		}
-		return bytes.Equal(match, line)
+		indexes = [][]int{{0, len(line)}}
+	} else {
+		indexes = m.regexp.FindAllIndex(line, -1)
 	}
 
-	// match whole words only
-	if m.opts.w {
-		indexes := m.regexp.FindAllIndex(line, -1)
-		for _, i := range indexes {
-			begin, end := i[0], i[1]
-			switch {
-			case begin == 0 && end == len(line):
-				return true
-			case begin == 0 && !syntax.IsWordChar(rune(line[end])):
-				return true
-			case end == len(line) && !syntax.IsWordChar(rune(line[begin-1])):
-				return true
-			}
+	var accepted [][]int
+	for _, i := range indexes {
+		if m.accept(line, i[0], i[1]) {
+			accepted = append(accepted, i)
 		}
+	}
+	return accepted
+}
+
+// accept reports whether the match line[begin:end] satisfies the word and
+// lookaround constraints.
+func (m *matcher) accept(line []byte, begin, end int) bool {
+	// match whole words only
+	if m.opts.w && !m.opts.x && !isWord(line, begin, end) {
+		return false
+	}
+
+	if m.notPrecededBy != nil && m.notPrecededBy.Match(line[:begin]) {
+		return false
+	}
+	if m.notFollowedBy != nil && m.notFollowedBy.Match(line[end:]) {
 		return false
 	}
+	return true
+}
 
+// isWord reports whether line[begin:end] is neither preceded nor followed by
+// a word character.
+func isWord(line []byte, begin, end int) bool {
+	if r, _ := utf8.DecodeLastRune(line[:begin]); begin > 0 && isWordChar(r) {
+		return false
+	}
+	if r, _ := utf8.DecodeRune(line[end:]); end < len(line) && isWordChar(r) {
+		return false
+	}
 	return true
 }
 
+// isWordChar reports whether r is a letter, digit or underscore. Unlike
+// syntax.IsWordChar, which is what \b goes by, it is not limited to ASCII, so
+// that the é in "café" is part of the word.
+func isWordChar(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
 type matchAll struct {
+	// patterns in the order given
+	patterns []string
+
 	each []*matcher
-	opts *Opts
+	// the patterns in each with combined set, joined as alternatives
+	combined *regexp.Regexp
+	fuzzy    []*fuzzy
+	bloom    *bloom
+	added    addedLines
+	// the start of multi-line entries
+	entryStart *regexp.Regexp
+	opts       *Opts
 }
 
 func (ms matchAll) Match(line []byte) bool {
-	var matches bool
+	if ms.opts.skipBinaryLines && binaryLine(line, ms.opts.binaryLineThreshold) {
+		return false
+	}
 
-	for _, m := range ms.each {
-		if m.match(line) {
-			matches = true
-			break
+	subjects, ok := ms.subjects(line)
+	if !ok {
+		return false
+	}
+
+	var matches bool
+	switch {
+	case ms.opts.allPatterns:
+		matches = true
+		for i := range ms.patterns {
+			if !ms.patternMatches(i, subjects) {
+				matches = false
+				break
+			}
 		}
+	case ms.opts.nonePatterns:
+		matches = !ms.anyMatches(subjects)
+	default:
+		matches = ms.anyMatches(subjects)
 	}
 
 	// invert match if necessary
 	return matches != ms.opts.v // xor
 }
 
+// subjects returns the parts of line that patterns are matched against, or
+// false if line has none and should never be selected.
+func (ms matchAll) subjects(line []byte) ([][]byte, bool) {
+	switch {
+	case ms.opts.jsonPath != "":
+		value, ok := jsonValue(line, ms.opts.jsonPath)
+		return [][]byte{value}, ok
+	case len(ms.opts.logfmtKeys) > 0:
+		values := logfmtValues(line, ms.opts.logfmtKeys)
+		return values, len(values) > 0
+	case ms.opts.csvField > 0:
+		value, ok := csvField(line, ms.opts.csvField)
+		return [][]byte{value}, ok
+	}
+	return [][]byte{line}, true
+}
+
+// patternIndex returns the index of the first pattern, in the order given,
+// that matches line, or -1 if none does.
+func (ms matchAll) patternIndex(line []byte) int {
+	if ms.opts.skipBinaryLines && binaryLine(line, ms.opts.binaryLineThreshold) {
+		return -1
+	}
+	subjects, ok := ms.subjects(line)
+	if !ok {
+		return -1
+	}
+	for i := range ms.patterns {
+		if ms.patternMatches(i, subjects) {
+			return i
+		}
+	}
+	return -1
+}
+
+// patternMatches reports whether pattern i matches any of subjects.
+func (ms matchAll) patternMatches(i int, subjects [][]byte) bool {
+	for _, subject := range subjects {
+		if ms.fuzzy != nil && ms.fuzzy[i].match(subject) ||
+			ms.fuzzy == nil && ms.each[i].match(subject) {
+			return true
+		}
+	}
+	return false
+}
+
+// anyMatches reports whether any pattern matches any of subjects.
+func (ms matchAll) anyMatches(subjects [][]byte) bool {
+	for _, subject := range subjects {
+		if ms.matches(subject) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether any pattern matches subject.
+func (ms matchAll) matches(subject []byte) bool {
+	// a bloom filter miss means no pattern can match
+	if ms.bloom != nil && !ms.bloom.mayMatch(subject) {
+		return false
+	}
+
+	if ms.combined != nil && ms.combined.Match(subject) {
+		return true
+	}
+	for _, m := range ms.each {
+		if !m.combined && m.match(subject) {
+			return true
+		}
+	}
+	for _, f := range ms.fuzzy {
+		if f.match(subject) {
+			return true
+		}
+	}
+	return false
+}
+
+// indexes returns the non-empty matches of every pattern in line, sorted and
+// with overlapping matches merged.
+func (ms matchAll) indexes(line []byte) [][]int {
+	var all [][]int
+	for _, m := range ms.each {
+		for _, i := range m.indexes(line) {
+			if i[0] < i[1] {
+				all = append(all, i)
+			}
+		}
+	}
+	sort.Slice(all, func(a, b int) bool { return all[a][0] < all[b][0] })
+
+	var merged [][]int
+	for _, i := range all {
+		if n := len(merged); n > 0 && i[0] < merged[n-1][1] {
+			if i[1] > merged[n-1][1] {
+				merged[n-1][1] = i[1]
+			}
+			continue
+		}
+		merged = append(merged, []int{i[0], i[1]})
+	}
+	return merged
+}
+
+// submatch returns the text of capture group group in the first pattern that
+// matches line, or the empty string if there is no such group.
+func (ms matchAll) submatch(line []byte, group int) string {
+	for _, m := range ms.each {
+		if !m.match(line) {
+			continue
+		}
+		if i := m.regexp.FindSubmatchIndex(line); 2*group+1 < len(i) && i[2*group] >= 0 {
+			return string(line[i[2*group]:i[2*group+1]])
+		}
+	}
+	return ""
+}
+
+// nonMatching returns the segments of line that fall between matches, joined
+// by sep.
+func (ms matchAll) nonMatching(line []byte, sep string) []byte {
+	var segments [][]byte
+	var begin int
+	for _, i := range ms.indexes(line) {
+		if i[0] > begin {
+			segments = append(segments, line[begin:i[0]])
+		}
+		begin = i[1]
+	}
+	if begin < len(line) {
+		segments = append(segments, line[begin:])
+	}
+	return bytes.Join(segments, []byte(sep))
+}
+
+// lookaround compiles expr wrapped by format, or returns nil if expr is empty.
+func (cmd *Grep) lookaround(expr, format string) (*regexp.Regexp, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	expr = fmt.Sprintf(format, expr)
+	if cmd.opts.i {
+		expr = "(?i)" + expr
+	}
+	return regexp.Compile(expr)
+}
+
 func (cmd *Grep) allMatcher() (*matchAll, error) {
-	var matchers []*matcher
+	if cmd.opts.conflict != nil {
+		return nil, cmd.opts.conflict
+	}
+	if cmd.opts.treeOutput && cmd.opts.o {
+		return nil, &OptionConflictError{Options: [2]string{"WithTreeOutput", "WithOnlyMatching"}}
+	}
+	if cmd.opts.treeOutput && cmd.opts.c {
+		return nil, &OptionConflictError{Options: [2]string{"WithTreeOutput", "WithCount"}}
+	}
+	switch cmd.opts.binaryFiles {
+	case "", "binary", "text", "without-match":
+	default:
+		return nil, fmt.Errorf("grep: unknown binary-files type %q", cmd.opts.binaryFiles)
+	}
+	switch cmd.opts.devices {
+	case "", "read", "skip":
+	default:
+		return nil, fmt.Errorf("grep: unknown devices method %q", cmd.opts.devices)
+	}
 
-	addExpr := func(expr string) error {
+	var (
+		matchers []*matcher
+		fuzzies  []*fuzzy
+		literals []string
+		literal  = true
+	)
+
+	// anchor lookaround patterns against the match
+	notPrecededBy, err := cmd.lookaround(cmd.opts.notPrecededBy, "(?:%s)$")
+	if err != nil {
+		return nil, err
+	}
+	notFollowedBy, err := cmd.lookaround(cmd.opts.notFollowedBy, "^(?:%s)")
+	if err != nil {
+		return nil, err
+	}
+
+	var patterns []string
+
+	compile := func(expr string) error {
+		patterns = append(patterns, expr)
+		if cmd.opts.fuzzy {
+			f, err := newFuzzy(expr, cmd.opts.maxEdits, cmd.opts.i)
+			if err != nil {
+				return err
+			}
+			fuzzies = append(fuzzies, f)
+			return nil
+		}
 		xflags := syntax.Perl // -p, --perl-regexp
+		switch cmd.opts.syntax {
+		case fixedStrings:
+			xflags |= syntax.Literal // -F, --fixed-strings
+		case basicRegexp:
+			// -G, --basic-regexp
+			translated, err := translateBRE(expr)
+			if err != nil {
+				return err
+			}
+			expr = translated
+		case extendedRegexp:
+			xflags = syntax.POSIX // -E, --extended-regexp
+		}
 		if cmd.opts.i {
 			xflags |= syntax.FoldCase // -i, --ignore-case
 		}
 		parsed, err := syntax.Parse(expr, xflags)
 		if err != nil {
+			if cmd.opts.syntax == perlRegexp {
+				// name the construct rather than the parser's complaint about it
+				if unsupported := unsupportedPerl(expr); unsupported != nil {
+					return unsupported
+				}
+			}
 			return err
 		}
 		regex, err := regexp.Compile(parsed.String())
 		if err != nil {
 			return err
 		}
-		matchers = append(matchers, &matcher{regexp: regex, opts: cmd.opts})
+		if cmd.opts.syntax == extendedRegexp {
+			regex.Longest()
+		}
+		m := &matcher{
+			regexp:        regex,
+			notPrecededBy: notPrecededBy,
+			notFollowedBy: notFollowedBy,
+			opts:          cmd.opts,
+		}
+		if cmd.opts.suffixSearch {
+			// parse again, since reversing rewrites the tree
+			parsed, _ := syntax.Parse(expr, xflags)
+			reverseSyntax(parsed)
+			if m.reversed, err = regexp.Compile(parsed.String()); err != nil {
+				return err
+			}
+		}
+		matchers = append(matchers, m)
+		prefix, complete := regex.LiteralPrefix()
+		literals = append(literals, prefix)
+		literal = literal && complete
+		return nil
+	}
+	addExpr := func(expr string) error {
+		if err := compile(expr); err != nil {
+			// name the pattern, since there may be many
+			return &PatternError{Pattern: expr, Err: err}
+		}
 		return nil
 	}
 
-	// obtain patterns from input, split on newlines. But only if regexps and files are unset.
-	if len(cmd.opts.e) == 0 && len(cmd.opts.f) == 0 {
+	// obtain patterns from input, split on newlines. Alongside regexps or
+	// files, an empty pattern is no pattern rather than one matching every line.
+	if cmd.pattern != "" || len(cmd.opts.e) == 0 && len(cmd.opts.f) == 0 {
 		for _, expr := range strings.Split(cmd.pattern, "\n") {
 			if err := addExpr(expr); err != nil {
 				return nil, err
@@ -298,7 +2196,7 @@ func (cmd *Grep) allMatcher() (*matchAll, error) {
 		}
 	}
 
-	// obtain patterns from regexp Opt, split on newlines
+	// obtain patterns from regexp Option, split on newlines
 	for _, pattern := range cmd.opts.e {
 		for _, expr := range strings.Split(pattern, "\n") {
 			if err := addExpr(expr); err != nil {
@@ -308,17 +2206,98 @@ func (cmd *Grep) allMatcher() (*matchAll, error) {
 	}
 
 	// obtain patterns from files, one per line
-	for _, file := range cmd.opts.f {
-		s := bufio.NewScanner(file)
-		for s.Scan() {
-			if err := addExpr(s.Text()); err != nil {
-				return nil, err
+	if err := cmd.readOnce(); err != nil {
+		return nil, err
+	}
+	for _, expr := range cmd.filePatterns {
+		if err := addExpr(expr); err != nil {
+			return nil, err
+		}
+	}
+
+	ms := &matchAll{patterns: patterns, each: matchers, fuzzy: fuzzies, opts: cmd.opts}
+	if cmd.opts.bloom && literal {
+		ms.bloom = newBloom(literals)
+	}
+
+	// test each line against every pattern at once, unless where a pattern
+	// matches needs checking match by match. Literals are only among the
+	// alternatives when they share a prefix to scan for: without one, the
+	// alternation is tried at every byte, and each literal is found faster
+	// on its own.
+	if !cmd.opts.noCombine && !cmd.opts.x && !cmd.opts.w && notPrecededBy == nil && notFollowedBy == nil && !cmd.opts.suffixSearch {
+		combine := func(withLiterals bool) (*regexp.Regexp, error) {
+			var alternatives []string
+			for _, m := range matchers {
+				_, complete := m.regexp.LiteralPrefix()
+				m.combined = withLiterals || !complete
+				if m.combined {
+					alternatives = append(alternatives, m.regexp.String())
+				}
+			}
+			if len(alternatives) < 2 {
+				for _, m := range matchers {
+					m.combined = false
+				}
+				return nil, nil
+			}
+			return regexp.Compile("(?:" + strings.Join(alternatives, ")|(?:") + ")")
+		}
+		combined, err := combine(true)
+		if err != nil {
+			return nil, err
+		}
+		if combined != nil {
+			if prefix, _ := combined.LiteralPrefix(); prefix == "" {
+				if combined, err = combine(false); err != nil {
+					return nil, err
+				}
 			}
 		}
-		if err := s.Err(); err != nil {
+		ms.combined = combined
+	}
+
+	// group lines into entries
+	if cmd.opts.entryStart != "" {
+		start, err := regexp.Compile(cmd.opts.entryStart)
+		if err != nil {
 			return nil, err
 		}
+		ms.entryStart = start
 	}
 
-	return &matchAll{each: matchers, opts: cmd.opts}, nil
+	// obtain the lines to consider from a diff
+	ms.added = cmd.added
+	return ms, nil
+}
+
+// readOnce reads the patterns in the files given by WithFiles and the diff
+// given by WithDiffFilter, which can only be read once, the first time it is
+// called, so that every search has them.
+func (cmd *Grep) readOnce() error {
+	cmd.read.Do(func() {
+		for _, file := range cmd.opts.f {
+			s := bufio.NewScanner(file)
+			for s.Scan() {
+				cmd.filePatterns = append(cmd.filePatterns, s.Text())
+			}
+			if err := s.Err(); err != nil {
+				cmd.readErr = err
+				return
+			}
+		}
+		if cmd.opts.diff != nil {
+			cmd.added, cmd.readErr = parseDiff(cmd.opts.diff)
+		}
+	})
+	return cmd.readErr
+}
+
+// Validate compiles every pattern, whether given to New, by WithRegexps or by
+// WithFiles, and checks the Options, returning the error a search would fail
+// with, if any, without searching anything. The error for a bad pattern
+// quotes it.
+func (cmd *Grep) Validate() error {
+	_, err := cmd.allMatcher()
+	return err
 }
@@ -1,9 +1,18 @@
 package grep_test
 
 import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"testing/iotest"
+	"time"
 
 	"github.com/kevin-cantwell/usrbin/pkg/grep"
 )
@@ -49,6 +58,39 @@ func TestGrep(t *testing.T) {
 			in:      "foo\nbar\nbaz\nfoobaz",
 			out:     "foo\nbar\nbaz\nfoobaz\n",
 		},
+		{
+			name:    "pattern+WithRegexps",
+			pattern: "baz",
+			opts:    []grep.Option{grep.WithRegexps("foo")},
+			in:      "foo\nbar\nbaz\nqux",
+			out:     "foo\nbaz\n",
+		},
+		{
+			name:    "pattern+WithRegexps/newlines",
+			pattern: "bar\nqux",
+			opts:    []grep.Option{grep.WithRegexps("foo")},
+			in:      "foo\nbar\nbaz\nqux",
+			out:     "foo\nbar\nqux\n",
+		},
+		{
+			name:    "pattern+WithRegexps+WithInvertMatch",
+			pattern: "baz",
+			opts:    []grep.Option{grep.WithRegexps("foo"), grep.WithInvertMatch()},
+			in:      "foo\nbar\nbaz\nqux",
+			out:     "bar\nqux\n",
+		},
+		{
+			name:    "case-sensitive",
+			pattern: "FOO",
+			in:      "foo\nFOO\nFoo",
+			out:     "FOO\n",
+		},
+		{
+			name: "case-sensitive/WithRegexps",
+			opts: []grep.Option{grep.WithRegexps("FOO", "bar")},
+			in:   "foo\nBAR\nbar",
+			out:  "bar\n",
+		},
 		{
 			name:    "WithIgnoreCase",
 			pattern: "FOO",
@@ -98,6 +140,41 @@ func TestGrep(t *testing.T) {
 			in:      "foo\nfoo bar\nbaz foo\nbar_foo_baz\nfoo-bar\nbar0foo",
 			out:     "foo\nfoo bar\nbaz foo\nfoo-bar\n",
 		},
+		{
+			name:    "WithWordRegexp/interior",
+			pattern: "bar",
+			opts:    []grep.Option{grep.WithWordRegexp()},
+			in:      "x bar y\nfoo-bar-baz\nxbary\nbarx (bar)\nx_bar y",
+			out:     "x bar y\nfoo-bar-baz\nbarx (bar)\n",
+		},
+		{
+			name:    "WithWordRegexp/utf-8",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithWordRegexp()},
+			in:      "→foo←\n日本 foo 本\n«foo»x\nxfoo→",
+			out:     "→foo←\n日本 foo 本\n«foo»x\n",
+		},
+		{
+			name:    "WithWordRegexp/café",
+			pattern: "caf",
+			opts:    []grep.Option{grep.WithWordRegexp()},
+			in:      "café\ncaf é\nécaf\ncaf",
+			out:     "caf é\ncaf\n",
+		},
+		{
+			name:    "WithWordRegexp/café-whole",
+			pattern: "café",
+			opts:    []grep.Option{grep.WithWordRegexp()},
+			in:      "un café noir\ncafés\nàcafé\ncafé_",
+			out:     "un café noir\n",
+		},
+		{
+			name:    "WithWordRegexp+WithOnlyMatching",
+			pattern: "[a-z]+",
+			opts:    []grep.Option{grep.WithWordRegexp(), grep.WithOnlyMatching()},
+			in:      "ab cd_ef gh",
+			out:     "ab\ngh\n",
+		},
 		{
 			name:    "WithLineRegexp",
 			pattern: "foo|baz",
@@ -105,18 +182,2259 @@ func TestGrep(t *testing.T) {
 			in:      "foo\nbar\nbaz\nfoobaz",
 			out:     "foo\nbaz\n",
 		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			in := strings.NewReader(tt.in)
-
-			out := grep.New(tt.pattern, tt.opts...).Exec(in)
-
-			if body, err := ioutil.ReadAll(out); err != nil {
-				t.Fatalf("got err: %#v", err)
-			} else if string(body) != tt.out {
-				t.Fatalf("got %q want %q", string(body), tt.out)
-			}
-		})
+		{
+			name:    "WithNonMatching",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithNonMatching()},
+			in:      "a foo b foo c\nbar\nfoo",
+			out:     "a  b  c\n\n",
+		},
+		{
+			name:    "WithNonMatching+WithNonMatchingSeparator",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithNonMatching(), grep.WithNonMatchingSeparator("|")},
+			in:      "a foo b foo c\nbar\nfoofoo x",
+			out:     "a | b | c\n x\n",
+		},
+		{
+			name:    "WithNonMatching/overlapping",
+			pattern: "ab\nbc",
+			opts:    []grep.Option{grep.WithNonMatching(), grep.WithNonMatchingSeparator("|")},
+			in:      "xabcy",
+			out:     "x|y\n",
+		},
+		{
+			name:    "WithNonMatching+WithWordRegexp",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithNonMatching(), grep.WithNonMatchingSeparator("|"), grep.WithWordRegexp()},
+			in:      "foo foobar foo",
+			out:     " foobar \n",
+		},
+		{
+			name:    "WithSectionBy",
+			pattern: `req=(\w+)`,
+			opts:    []grep.Option{grep.WithSectionBy(1)},
+			in:      "a req=1\nb req=2\nnoise\nc req=1\nd req=3\ne req=2",
+			out:     "== 1 ==\na req=1\nc req=1\n== 2 ==\nb req=2\ne req=2\n== 3 ==\nd req=3\n",
+		},
+		{
+			name:    "WithSectionBy/whole-match",
+			pattern: `ERROR|WARN`,
+			opts:    []grep.Option{grep.WithSectionBy(0)},
+			in:      "WARN a\nERROR b\nINFO c\nWARN d",
+			out:     "== WARN ==\nWARN a\nWARN d\n== ERROR ==\nERROR b\n",
+		},
+		{
+			name:    "WithSectionBy/non-participating",
+			pattern: `id=(\d+)|none`,
+			opts:    []grep.Option{grep.WithSectionBy(1)},
+			in:      "none a\nid=7 b\nnone c",
+			out:     "==  ==\nnone a\nnone c\n== 7 ==\nid=7 b\n",
+		},
+		{
+			name:    "WithJSONPath",
+			pattern: "^/api",
+			opts:    []grep.Option{grep.WithJSONPath("req.url")},
+			in: `{"msg":"a","req":{"url":"/api/users"}}
+{"msg":"/api in message","req":{"url":"/static"}}
+not json /api
+{"msg":"no req"}
+{"msg":"b","req":{"url":"/api/orders"}}`,
+			out: `{"msg":"a","req":{"url":"/api/users"}}
+{"msg":"b","req":{"url":"/api/orders"}}
+`,
+		},
+		{
+			name:    "WithJSONPath/non-string",
+			pattern: "^5",
+			opts:    []grep.Option{grep.WithJSONPath("res.status")},
+			in:      `{"res":{"status":503}}` + "\n" + `{"res":{"status":200}}` + "\n" + `{"res":{"status":"5xx"}}`,
+			out:     `{"res":{"status":503}}` + "\n" + `{"res":{"status":"5xx"}}` + "\n",
+		},
+		{
+			name:    "WithJSONPath/array",
+			pattern: "admin",
+			opts:    []grep.Option{grep.WithJSONPath("roles.0")},
+			in:      `{"roles":["admin","user"]}` + "\n" + `{"roles":["user","admin"]}` + "\n" + `{"roles":[]}`,
+			out:     `{"roles":["admin","user"]}` + "\n",
+		},
+		{
+			name:    "WithJSONPath+WithInvertMatch",
+			pattern: "^/api",
+			opts:    []grep.Option{grep.WithJSONPath("url"), grep.WithInvertMatch()},
+			in:      `{"url":"/api"}` + "\n" + `{"url":"/home"}` + "\n" + `{"path":"/home"}` + "\nplain",
+			out:     `{"url":"/home"}` + "\n",
+		},
+		{
+			name:    "WithFuzzy",
+			pattern: "helo",
+			opts:    []grep.Option{grep.WithFuzzy(1)},
+			in:      "say hello world\nhelo\nhxlo there\ngoodbye\nhlo\nh e l o",
+			out:     "say hello world\nhelo\nhxlo there\nhlo\n",
+		},
+		{
+			name:    "WithFuzzy/exact",
+			pattern: "helo",
+			opts:    []grep.Option{grep.WithFuzzy(0)},
+			in:      "say hello world\nhelo\nxhelox",
+			out:     "helo\nxhelox\n",
+		},
+		{
+			name:    "WithFuzzy+WithIgnoreCase",
+			pattern: "HELO",
+			opts:    []grep.Option{grep.WithFuzzy(1), grep.WithIgnoreCase()},
+			in:      "Hello\nbye",
+			out:     "Hello\n",
+		},
+		{
+			name:    "WithFuzzy/literal",
+			pattern: "a.c",
+			opts:    []grep.Option{grep.WithFuzzy(0)},
+			in:      "abc\na.c",
+			out:     "a.c\n",
+		},
+		{
+			name:    "WithFuzzy/multibyte",
+			pattern: "café",
+			opts:    []grep.Option{grep.WithFuzzy(1)},
+			in:      "cafe\ncafé au lait\ncaxxx",
+			out:     "cafe\ncafé au lait\n",
+		},
+		{
+			name:    "WithLogfmt",
+			pattern: "^user logged",
+			opts:    []grep.Option{grep.WithLogfmt("msg")},
+			in: `level=info msg="user logged in" user=bob
+level=info msg=other note="user logged in"
+level=warn msg="user logged out"
+user logged in
+level=info msg=user`,
+			out: `level=info msg="user logged in" user=bob
+level=warn msg="user logged out"
+`,
+		},
+		{
+			name:    "WithLogfmt/keys",
+			pattern: "^(error|bob)$",
+			opts:    []grep.Option{grep.WithLogfmt("level", "user")},
+			in:      "level=error user=amy\nlevel=info user=bob\nlevel=info user=\"bob jr\"\nmsg=error",
+			out:     "level=error user=amy\nlevel=info user=bob\n",
+		},
+		{
+			name:    "WithLogfmt/escaped-quote",
+			pattern: `say "hi"`,
+			opts:    []grep.Option{grep.WithLogfmt("msg")},
+			in:      `msg="say \"hi\" now" x=1` + "\n" + `msg="say hi"`,
+			out:     `msg="say \"hi\" now" x=1` + "\n",
+		},
+		{
+			name:    "WithLogfmt+WithInvertMatch",
+			pattern: "error",
+			opts:    []grep.Option{grep.WithLogfmt("level"), grep.WithInvertMatch()},
+			in:      "level=error\nlevel=info\nmsg=error\nlevel",
+			out:     "level=info\nlevel\n",
+		},
+		{
+			name:    "WithSkipBinaryLines",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithSkipBinaryLines()},
+			in:      "foo one\nfoo\x00\x01\x02\nfoo \x1b[1m bold\nfoo\x01\x02\x03\x04\ncafé foo\nfoo\xff\xfe\xfd\xfc",
+			out:     "foo one\nfoo \x1b[1m bold\ncafé foo\n",
+		},
+		{
+			name:    "WithSkipBinaryLines+WithBinaryLineThreshold",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithSkipBinaryLines(), grep.WithBinaryLineThreshold(0.05)},
+			in:      "foo one\nfoo \x1b[1m bold\nfoo\x00",
+			out:     "foo one\n",
+		},
+		{
+			name:    "WithSkipBinaryLines+WithInvertMatch",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithSkipBinaryLines(), grep.WithInvertMatch()},
+			in:      "foo\nbar\nbar\x00baz",
+			out:     "bar\n",
+		},
+		{
+			name:    "WithInitialTab+WithLineNumber",
+			pattern: "a",
+			opts:    []grep.Option{grep.WithInitialTab(), grep.WithLineNumber()},
+			in:      "a\tb\nc\nbba\td\n",
+			out:     "1:\ta\tb\n3:\tbba\td\n",
+		},
+		{
+			name:    "WithInitialTab+WithLineNumber/context",
+			pattern: "a",
+			opts:    []grep.Option{grep.WithInitialTab(), grep.WithLineNumber(), grep.WithBeforeContext(1)},
+			in:      "c\na\n",
+			out:     "1-\tc\n2:\ta\n",
+		},
+		{
+			name:    "WithInitialTab+WithLineNumber/empty line",
+			pattern: "^$",
+			opts:    []grep.Option{grep.WithInitialTab(), grep.WithLineNumber()},
+			in:      "a\n\n",
+			out:     "2:\n",
+		},
+		{
+			name:    "WithInitialTab+WithByteOffset+WithLabel",
+			pattern: "a",
+			opts:    []grep.Option{grep.WithInitialTab(), grep.WithByteOffset(), grep.WithFilename(), grep.WithLabel("in")},
+			in:      "c\na\n",
+			out:     "in:2:\ta\n",
+		},
+		{
+			name:    "WithInitialTab",
+			pattern: "a",
+			opts:    []grep.Option{grep.WithInitialTab()},
+			in:      "a\tb\n",
+			out:     "a\tb\n",
+		},
+		{
+			name:    "no final newline",
+			pattern: "foo",
+			in:      "foo",
+			out:     "foo\n",
+		},
+		{
+			name:    "no final newline/WithCount",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithCount()},
+			in:      "bar\nfoo",
+			out:     "1\n",
+		},
+		{
+			name:    "no final newline/WithOnlyMatching",
+			pattern: "o+",
+			opts:    []grep.Option{grep.WithOnlyMatching()},
+			in:      "foo",
+			out:     "oo\n",
+		},
+		{
+			name:    "no final newline/WithInvertMatch",
+			pattern: "bar",
+			opts:    []grep.Option{grep.WithInvertMatch()},
+			in:      "bar\nfoo",
+			out:     "foo\n",
+		},
+		{
+			name:    "no final newline/WithPreserveEOL",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithPreserveEOL()},
+			in:      "foo 1\nfoo 2",
+			out:     "foo 1\nfoo 2",
+		},
+		{
+			name:    "CRLF",
+			pattern: "o$",
+			in:      "foo\r\nbar\r\nzoo\n",
+			out:     "foo\nzoo\n",
+		},
+		{
+			name:    "WithBinary",
+			pattern: "o$",
+			opts:    []grep.Option{grep.WithBinary()},
+			in:      "foo\r\nbar\r\nzoo\n",
+			out:     "zoo\n",
+		},
+		{
+			name:    "WithBinary/CR kept",
+			pattern: "o\r$",
+			opts:    []grep.Option{grep.WithBinary()},
+			in:      "foo\r\nbar\r\nzoo",
+			out:     "foo\r\n",
+		},
+		{
+			name:    "WithBinaryFiles",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithBinaryFiles("binary")},
+			in:      "foo 1\nbar\x00\nfoo 2\n",
+			out:     "Binary file (standard input) matches\n",
+		},
+		{
+			name:    "WithBinaryFiles/no match",
+			pattern: "baz",
+			opts:    []grep.Option{grep.WithBinaryFiles("binary")},
+			in:      "foo 1\nbar\x00\nfoo 2\n",
+			out:     "",
+		},
+		{
+			name:    "WithBinaryFiles/text",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithBinaryFiles("binary")},
+			in:      "foo 1\nbar\nfoo 2\n",
+			out:     "foo 1\nfoo 2\n",
+		},
+		{
+			name:    "WithBinaryFiles+WithCount",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithBinaryFiles("binary"), grep.WithCount()},
+			in:      "foo 1\nbar\x00\nfoo 2\n",
+			out:     "2\n",
+		},
+		{
+			name:    "WithBinaryFiles+WithNullData",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithBinaryFiles("binary"), grep.WithNullData()},
+			in:      "foo 1\x00bar\x00",
+			out:     "foo 1\x00",
+		},
+		{
+			name:    "WithText",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithText()},
+			in:      "foo 1\nbar\x00\nfoo\x00 2\n",
+			out:     "foo 1\nfoo\x00 2\n",
+		},
+		{
+			name:    "WithBinaryWithoutMatch",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithBinaryWithoutMatch()},
+			in:      "foo 1\nbar\x00\nfoo 2\n",
+			out:     "",
+		},
+		{
+			name:    "WithBinaryWithoutMatch/text",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithBinaryWithoutMatch()},
+			in:      "foo 1\nbar\nfoo 2\n",
+			out:     "foo 1\nfoo 2\n",
+		},
+		{
+			name:    "WithParagraphMode",
+			pattern: `(?m)^\s+port = 8080$`,
+			opts:    []grep.Option{grep.WithParagraphMode()},
+			in:      "\n\n[a]\n  port = 80\n\n\n\n[b]\n  host = x\n  port = 8080\n\n[c]\n  port = 8080\n\n",
+			out:     "[b]\n  host = x\n  port = 8080\n\n[c]\n  port = 8080\n",
+		},
+		{
+			name:    "WithParagraphMode/multiline-pattern",
+			pattern: `host = x\s+port`,
+			opts:    []grep.Option{grep.WithParagraphMode()},
+			in:      "[a]\nhost = x\n\nport = 1\n\n[b]\nhost = x\nport = 2",
+			out:     "[b]\nhost = x\nport = 2\n",
+		},
+		{
+			name:    "WithParagraphMode+WithInvertMatch",
+			pattern: `port`,
+			opts:    []grep.Option{grep.WithParagraphMode(), grep.WithInvertMatch()},
+			in:      "a\nport\n\nb\nc\n\nd",
+			out:     "b\nc\n\nd\n",
+		},
+		{
+			name:    "WithPerLineCount",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithPerLineCount()},
+			in:      "foo\nbar\nfoo foo foo\nfoofoo bar foo",
+			out:     "1:foo\n3:foo foo foo\n3:foofoo bar foo\n",
+		},
+		{
+			name:    "WithPerLineCount+WithWordRegexp",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithPerLineCount(), grep.WithWordRegexp()},
+			in:      "foo foobar foo\nfoo",
+			out:     "2:foo foobar foo\n1:foo\n",
+		},
+		{
+			name:    "WithPerLineCount+WithInvertMatch",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithPerLineCount(), grep.WithInvertMatch()},
+			in:      "foo\nbar",
+			out:     "0:bar\n",
+		},
+		{
+			name:    "WithEntryStart",
+			pattern: "NullPointerException",
+			opts:    []grep.Option{grep.WithEntryStart(`^\d{4}-\d\d-\d\d `)},
+			in: `2019-01-01 INFO starting
+2019-01-01 ERROR request failed
+java.lang.NullPointerException
+	at com.example.Foo.bar(Foo.java:10)
+	at com.example.Main.main(Main.java:3)
+2019-01-02 INFO NullPointerException handled elsewhere
+2019-01-02 ERROR other
+java.io.IOException
+`,
+			out: `2019-01-01 ERROR request failed
+java.lang.NullPointerException
+	at com.example.Foo.bar(Foo.java:10)
+	at com.example.Main.main(Main.java:3)
+2019-01-02 INFO NullPointerException handled elsewhere
+`,
+		},
+		{
+			name:    "WithEntryStart/preamble",
+			pattern: "x",
+			opts:    []grep.Option{grep.WithEntryStart(`^#`)},
+			in:      "x preamble\nmore\n# a\nb\n# x\n",
+			out:     "x preamble\nmore\n# x\n",
+		},
+		{
+			name:    "WithEntryStart+WithInvertMatch",
+			pattern: "Exception",
+			opts:    []grep.Option{grep.WithEntryStart(`^\[`), grep.WithInvertMatch()},
+			in:      "[1] ok\n[2] bad\nException\n[3] ok\n  detail",
+			out:     "[1] ok\n[3] ok\n  detail\n",
+		},
+		{
+			name:    "WithNullData",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithNullData()},
+			in:      "a foo\x00bar\x00multi\nline foo\nrecord\x00foo",
+			out:     "a foo\x00multi\nline foo\nrecord\x00foo\x00",
+		},
+		{
+			name:    "WithNullData/across-lines",
+			pattern: `foo\nbar`,
+			opts:    []grep.Option{grep.WithNullData()},
+			in:      "foo\nbar\x00foo bar\x00x foo\nbar y\x00",
+			out:     "foo\nbar\x00x foo\nbar y\x00",
+		},
+		{
+			name:    "WithNullData+WithLineRegexp",
+			pattern: `a\nb|c`,
+			opts:    []grep.Option{grep.WithNullData(), grep.WithLineRegexp()},
+			in:      "a\nb\x00c\nd\x00c",
+			out:     "a\nb\x00c\x00",
+		},
+		{
+			name:    "WithNullData+WithCount",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithNullData(), grep.WithCount()},
+			in:      "foo\nfoo\x00bar\nfoo\x00baz",
+			out:     "2\n",
+		},
+		{
+			name:    "WithNullData+WithContext",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithNullData(), grep.WithContext(1)},
+			in:      "foo\x00a\x00b\x00c\x00foo",
+			out:     "foo\x00a\x00--\nc\x00foo\x00",
+		},
+		{
+			name:    "WithByteOffset",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithByteOffset()},
+			in:      "foo\nbar\nbaz foo\nfoo",
+			out:     "0:foo\n8:baz foo\n16:foo\n",
+		},
+		{
+			name:    "WithByteOffset/multibyte",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithByteOffset()},
+			in:      "héllo foo\n日本 foo\nbar\nfoo",
+			out:     "0:héllo foo\n11:日本 foo\n26:foo\n",
+		},
+		{
+			name:    "WithByteOffset/CRLF",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithByteOffset()},
+			in:      "foo\r\nbar\r\nfoo\r\n",
+			out:     "0:foo\n10:foo\n",
+		},
+		{
+			name:    "WithByteOffset+WithLineNumber",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithByteOffset(), grep.WithLineNumber()},
+			in:      "ä\nfoo",
+			out:     "2:3:foo\n",
+		},
+		{
+			name:    "WithNullData+WithByteOffset",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithNullData(), grep.WithByteOffset()},
+			in:      "a foo\x00bar\x00xx\nfoo\x00foo",
+			out:     "0:a foo\x0010:xx\nfoo\x0017:foo\x00",
+		},
+		{
+			name:    "WithNullData+WithByteOffset+WithRecordRelativeOffset",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithNullData(), grep.WithByteOffset(), grep.WithRecordRelativeOffset()},
+			in:      "a foo\x00bar\x00xx\nfoo\x00foo",
+			out:     "2:a foo\x003:xx\nfoo\x000:foo\x00",
+		},
+		{
+			name:    "WithNotPrecededBy",
+			pattern: "bar",
+			opts:    []grep.Option{grep.WithNotPrecededBy("foo")},
+			in:      "bar\nfoobar\nfoo bar\nfoobar bar\nfoofoobar",
+			out:     "bar\nfoo bar\nfoobar bar\n",
+		},
+		{
+			name:    "WithNotPrecededBy/regexp",
+			pattern: `\d+`,
+			opts:    []grep.Option{grep.WithNotPrecededBy(`[$€]`)},
+			in:      "$5\n€7\n9 items\n$1 and 2",
+			out:     "9 items\n$1 and 2\n",
+		},
+		{
+			name:    "WithNotPrecededBy+WithIgnoreCase",
+			pattern: "bar",
+			opts:    []grep.Option{grep.WithNotPrecededBy("foo"), grep.WithIgnoreCase()},
+			in:      "FOOBAR\nBAR",
+			out:     "BAR\n",
+		},
+		{
+			name:    "WithNotFollowedBy",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithNotFollowedBy(`\.txt`)},
+			in:      "foo.txt\nfoo.go\nfoo\nfoo.txt foo.md",
+			out:     "foo.go\nfoo\nfoo.txt foo.md\n",
+		},
+		{
+			name:    "WithNotFollowedBy+WithPerLineCount",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithNotFollowedBy("bar"), grep.WithPerLineCount()},
+			in:      "foobar foo foobaz foobar",
+			out:     "2:foobar foo foobaz foobar\n",
+		},
+		{
+			name:    "WithCSVField",
+			pattern: "^admin$",
+			opts:    []grep.Option{grep.WithCSVField(2)},
+			in:      "1,admin,alice\n2,user,admin\n3,\"admin\",bob",
+			out:     "1,admin,alice\n3,\"admin\",bob\n",
+		},
+		{
+			name:    "WithCSVField/quoted-comma",
+			pattern: "^Smith, John$",
+			opts:    []grep.Option{grep.WithCSVField(2)},
+			in:      "1,\"Smith, John\",42\n2,Smith, John,42\n3,\"Doe, Jane\",7",
+			out:     "1,\"Smith, John\",42\n",
+		},
+		{
+			name:    "WithCSVField/column-after-quoted-comma",
+			pattern: "^7$",
+			opts:    []grep.Option{grep.WithCSVField(3)},
+			in:      "1,\"Smith, John\",42\n3,\"Doe, Jane\",7\n4,Doe,7",
+			out:     "3,\"Doe, Jane\",7\n4,Doe,7\n",
+		},
+		{
+			name:    "WithCSVField/escaped-quote",
+			pattern: `^say "hi"$`,
+			opts:    []grep.Option{grep.WithCSVField(1)},
+			in:      "\"say \"\"hi\"\"\",x\nsay hi,x",
+			out:     "\"say \"\"hi\"\"\",x\n",
+		},
+		{
+			name:    "WithCSVField+WithInvertMatch",
+			pattern: "admin",
+			opts:    []grep.Option{grep.WithCSVField(2), grep.WithInvertMatch()},
+			in:      "1,admin\n2,user\n3\n4,\"bad",
+			out:     "2,user\n",
+		},
+		{
+			name:    "WithCaret",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithCaret()},
+			in:      "x := foo(bar)\nnone\nfoo foo",
+			out:     "x := foo(bar)\n     ^^^\nfoo foo\n^^^\n",
+		},
+		{
+			name:    "WithCaret/tab",
+			pattern: "bar",
+			opts:    []grep.Option{grep.WithCaret()},
+			in:      "\tx\tbar",
+			out:     "\tx\tbar\n\t \t^^^\n",
+		},
+		{
+			name:    "WithCaret/multibyte",
+			pattern: "b+",
+			opts:    []grep.Option{grep.WithCaret()},
+			in:      "héllo 日本 bb",
+			out:     "héllo 日本 bb\n           ^^\n",
+		},
+		{
+			name:    "WithCaret/wide-match",
+			pattern: "日本",
+			opts:    []grep.Option{grep.WithCaret()},
+			in:      "a日本",
+			out:     "a日本\n ^^^^\n",
+		},
+		{
+			name:    "WithAllCarets",
+			pattern: "o+",
+			opts:    []grep.Option{grep.WithAllCarets()},
+			in:      "foo bar boo",
+			out:     "foo bar boo\n ^^      ^^\n",
+		},
+		{
+			name:    "WithCaret+WithByteOffset",
+			pattern: "b",
+			opts:    []grep.Option{grep.WithCaret(), grep.WithByteOffset()},
+			in:      "a\n\tab",
+			out:     "2:\tab\n  \t ^\n",
+		},
+		{
+			name:    "WithCaret+WithInvertMatch",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithCaret(), grep.WithInvertMatch()},
+			in:      "foo\nbar",
+			out:     "bar\n",
+		},
+		{
+			name: "WithFirstMatchWins",
+			opts: []grep.Option{grep.WithRegexps("error", "timeout", "."), grep.WithFirstMatchWins()},
+			in:   "error: timeout\ntimeout\nok\n\nrequest timeout error",
+			out:  "0:error: timeout\n1:timeout\n2:ok\n0:request timeout error\n",
+		},
+		{
+			name: "WithFirstMatchWins+WithInvertMatch",
+			opts: []grep.Option{grep.WithRegexps("a", "b"), grep.WithFirstMatchWins(), grep.WithInvertMatch()},
+			in:   "a\nc",
+			out:  "c\n",
+		},
+		{
+			name:    "WithLineNumber",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithLineNumber()},
+			in:      "foo\nbar\nbaz foo",
+			out:     "1:foo\n3:baz foo\n",
+		},
+		{
+			name:    "WithLineNumber+WithInvertMatch",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithLineNumber(), grep.WithInvertMatch()},
+			in:      "foo\nbar\nbaz foo\nqux",
+			out:     "2:bar\n4:qux\n",
+		},
+		{
+			name: "WithLineNumber+WithRegexps",
+			opts: []grep.Option{grep.WithLineNumber(), grep.WithRegexps("foo", "qux")},
+			in:   "foo\nbar\nbaz\nqux",
+			out:  "1:foo\n4:qux\n",
+		},
+		{
+			name:    "WithLineNumber+WithNullData",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithLineNumber(), grep.WithNullData()},
+			in:      "a\nb\x00foo\nbar\x00c\x00foo",
+			out:     "2:foo\nbar\x004:foo\x00",
+		},
+		{
+			name:    "WithColor",
+			pattern: "o+",
+			opts:    []grep.Option{grep.WithColor("always")},
+			in:      "foo boo\nbar",
+			out:     "f\x1b[01;31m\x1b[Koo\x1b[m\x1b[K b\x1b[01;31m\x1b[Koo\x1b[m\x1b[K\n",
+		},
+		{
+			name:    "WithColor/auto",
+			pattern: "o+",
+			opts:    []grep.Option{grep.WithColor("auto")},
+			in:      "foo",
+			out:     "foo\n",
+		},
+		{
+			name:    "WithColor/auto+WithTerminal",
+			pattern: "o+",
+			opts:    []grep.Option{grep.WithTerminal(true), grep.WithColor("auto")},
+			in:      "foo",
+			out:     "f\x1b[01;31m\x1b[Koo\x1b[m\x1b[K\n",
+		},
+		{
+			name:    "WithColor/auto+WithTerminal(false)",
+			pattern: "o+",
+			opts:    []grep.Option{grep.WithColor("auto"), grep.WithTerminal(false)},
+			in:      "foo",
+			out:     "foo\n",
+		},
+		{
+			name:    "WithColor/always+WithTerminal(false)",
+			pattern: "o+",
+			opts:    []grep.Option{grep.WithColor("always"), grep.WithTerminal(false)},
+			in:      "foo",
+			out:     "f\x1b[01;31m\x1b[Koo\x1b[m\x1b[K\n",
+		},
+		{
+			name:    "WithColor+WithByteOffset",
+			pattern: "o+",
+			opts:    []grep.Option{grep.WithColor("always"), grep.WithByteOffset()},
+			in:      "foo\nboo",
+			out:     "0:f\x1b[01;31m\x1b[Koo\x1b[m\x1b[K\n4:b\x1b[01;31m\x1b[Koo\x1b[m\x1b[K\n",
+		},
+		{
+			name:    "WithColor+WithOnlyMatching+WithByteOffset",
+			pattern: "o+",
+			opts:    []grep.Option{grep.WithColor("always"), grep.WithOnlyMatching(), grep.WithByteOffset()},
+			in:      "foo boo\nzoo",
+			out:     "1:\x1b[01;31m\x1b[Koo\x1b[m\x1b[K\n5:\x1b[01;31m\x1b[Koo\x1b[m\x1b[K\n9:\x1b[01;31m\x1b[Koo\x1b[m\x1b[K\n",
+		},
+		{
+			name:    "WithColor/never",
+			pattern: "o+",
+			opts:    []grep.Option{grep.WithColor("never")},
+			in:      "foo boo\nbar",
+			out:     "foo boo\n",
+		},
+		{
+			name:    "WithPassthru",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithPassthru()},
+			in:      "foo\nbar\nbaz foo",
+			out:     "foo\nbar\nbaz foo\n",
+		},
+		{
+			name:    "WithPassthru+WithLineNumber",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithPassthru(), grep.WithLineNumber()},
+			in:      "foo\nbar\nbaz\nbaz foo",
+			out:     "1:foo\n2-bar\n3-baz\n4:baz foo\n",
+		},
+		{
+			name:    "WithPassthru+WithLineNumber+WithColor",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithPassthru(), grep.WithLineNumber(), grep.WithColor("always")},
+			in:      "a foo\nbar\nfoo",
+			out: "1:a \x1b[01;31m\x1b[Kfoo\x1b[m\x1b[K\n" +
+				"2-bar\n" +
+				"3:\x1b[01;31m\x1b[Kfoo\x1b[m\x1b[K\n",
+		},
+		{
+			name:    "WithShowTrailingWhitespace",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithShowTrailingWhitespace()},
+			in:      "foo bar \t \nfoo\tbar\nbar  \n  foo",
+			out:     "foo bar·→·\nfoo\tbar\n  foo\n",
+		},
+		{
+			name:    "WithShowTrailingWhitespace/blank",
+			pattern: "^[ \t]*$",
+			opts:    []grep.Option{grep.WithShowTrailingWhitespace()},
+			in:      "a\n \t\n\nb",
+			out:     "·→\n\n",
+		},
+		{
+			name:    "WithShowTrailingWhitespace+WithColor",
+			pattern: `o\s*$`,
+			opts:    []grep.Option{grep.WithShowTrailingWhitespace(), grep.WithColor("always")},
+			in:      "a foo  \nfoo",
+			out:     "a fo\x1b[01;31m\x1b[Ko··\x1b[m\x1b[K\nfo\x1b[01;31m\x1b[Ko\x1b[m\x1b[K\n",
+		},
+		{
+			name: "WithAllPatterns",
+			opts: []grep.Option{grep.WithRegexps("error", "disk"), grep.WithAllPatterns()},
+			in:   "error: disk full\nerror: timeout\ndisk ok\ndisk error",
+			out:  "error: disk full\ndisk error\n",
+		},
+		{
+			name: "WithAllPatterns+WithInvertMatch",
+			opts: []grep.Option{grep.WithRegexps("error", "disk"), grep.WithAllPatterns(), grep.WithInvertMatch()},
+			in:   "error: disk full\nerror: timeout\ndisk ok\ndisk error",
+			out:  "error: timeout\ndisk ok\n",
+		},
+		{
+			name: "WithAllPatterns+WithLogfmt",
+			opts: []grep.Option{grep.WithRegexps("^GET$", "^/api"), grep.WithAllPatterns(), grep.WithLogfmt("method", "path")},
+			in:   "method=GET path=/api/users\nmethod=POST path=/api/users\nmethod=GET path=/home",
+			out:  "method=GET path=/api/users\n",
+		},
+		{
+			name: "WithNonePatterns",
+			opts: []grep.Option{grep.WithRegexps("error", "warn"), grep.WithNonePatterns()},
+			in:   "error: disk full\ninfo: started\nwarn: slow\ninfo: done",
+			out:  "info: started\ninfo: done\n",
+		},
+		{
+			name:    "WithSuffixSearch",
+			pattern: `status=[45]\d\d$`,
+			opts:    []grep.Option{grep.WithSuffixSearch()},
+			in:      "GET / status=200\nGET /x status=404\nstatus=500 retrying\nPOST / status=503",
+			out:     "GET /x status=404\nPOST / status=503\n",
+		},
+		{
+			name:    "WithSuffixSearch/multibyte",
+			pattern: `(ö|ü)+ber$`,
+			opts:    []grep.Option{grep.WithSuffixSearch()},
+			in:      "drüber\ndrober\nüberall\nxöüber",
+			out:     "drüber\nxöüber\n",
+		},
+		{
+			name:    "WithContext/overlapping",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithBeforeContext(2), grep.WithAfterContext(1), grep.WithLineNumber()},
+			in:      "1\n2 foo\n3\n4 foo\n5\n6\n7\n8\n9 foo",
+			out:     "1-1\n2:2 foo\n3-3\n4:4 foo\n5-5\n--\n7-7\n8-8\n9:9 foo\n",
+		},
+		{
+			name:    "WithContext/start-and-end",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithContext(3)},
+			in:      "foo 1\na\nb\nc\nd\ne\nf\ng\nfoo 2",
+			out:     "foo 1\na\nb\nc\n--\ne\nf\ng\nfoo 2\n",
+		},
+		{
+			name:    "WithContext/shorthand",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithContext(1)},
+			in:      "a\nb\nfoo\nc\nd",
+			out:     "b\nfoo\nc\n",
+		},
+		{
+			name:    "WithContext/clustered",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithContext(2), grep.WithLineNumber()},
+			in:      "1\n2\n3\n4 foo\n5\n6 foo\n7 foo\n8\n9\n10 foo\n11\n12\n13\n14",
+			out:     "2-2\n3-3\n4:4 foo\n5-5\n6:6 foo\n7:7 foo\n8-8\n9-9\n10:10 foo\n11-11\n12-12\n",
+		},
+		{
+			name:    "WithMergeContext",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithContext(2), grep.WithMergeContext(), grep.WithLineNumber()},
+			in:      "1\n2\n3\n4 foo\n5\n6 foo\n7 foo\n8\n9\n10 foo\n11\n12\n13\n14",
+			out:     "2-2\n3-3\n4:4 foo\n5-5\n6:6 foo\n7:7 foo\n8-8\n9-9\n10:10 foo\n11-11\n12-12\n",
+		},
+		{
+			name:    "WithContext/adjacent",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithContext(1)},
+			in:      "a\nfoo\nb\nc\nfoo\nd",
+			out:     "a\nfoo\nb\nc\nfoo\nd\n",
+		},
+		{
+			name:    "WithContext/separate",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithContext(1), grep.WithLineNumber()},
+			in:      "foo\n2\n3\n4\n5 foo\n6",
+			out:     "1:foo\n2-2\n--\n4-4\n5:5 foo\n6-6\n",
+		},
+		{
+			name:    "WithBeforeContext/separate",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithBeforeContext(1)},
+			in:      "a\nb\nfoo\nc\nd\nfoo",
+			out:     "b\nfoo\n--\nd\nfoo\n",
+		},
+		{
+			name:    "WithAfterContext/separate",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithAfterContext(1)},
+			in:      "foo\na\nb\nfoo\nfoo\nc\nd",
+			out:     "foo\na\n--\nfoo\nfoo\nc\n",
+		},
+		{
+			name:    "WithReplacePreview",
+			pattern: `(\w+)@example\.com`,
+			opts:    []grep.Option{grep.WithReplacePreview("$1@example.org")},
+			in:      "to: alice@example.com\nfrom: nobody\ncc: bob@example.com, carol@example.com",
+			out: "-to: alice@example.com\n+to: alice@example.org\n" +
+				"-cc: bob@example.com, carol@example.com\n+cc: bob@example.org, carol@example.org\n",
+		},
+		{
+			name:    "WithReplacePreview/named",
+			pattern: `(?P<key>\w+)=(?P<value>\w+)`,
+			opts:    []grep.Option{grep.WithReplacePreview("${value}=${key}")},
+			in:      "a=1 b=2",
+			out:     "-a=1 b=2\n+1=a 2=b\n",
+		},
+		{
+			name:    "WithReplacePreview+WithWordRegexp",
+			pattern: "cat",
+			opts:    []grep.Option{grep.WithReplacePreview("dog"), grep.WithWordRegexp()},
+			in:      "cat",
+			out:     "-cat\n+dog\n",
+		},
+		{
+			name:    "WithReplacePreview+WithInvertMatch",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithReplacePreview("bar"), grep.WithInvertMatch()},
+			in:      "foo\nbaz",
+			out:     "",
+		},
+		{
+			name:    "WithDimContext",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithDimContext(), grep.WithColor("always"), grep.WithContext(1), grep.WithLineNumber()},
+			in:      "a\nx foo\nb\nc\nd",
+			out: "1-\x1b[2m\x1b[Ka\x1b[m\x1b[K\n" +
+				"2:x \x1b[01;31m\x1b[Kfoo\x1b[m\x1b[K\n" +
+				"3-\x1b[2m\x1b[Kb\x1b[m\x1b[K\n",
+		},
+		{
+			name:    "WithDimContext+WithPassthru",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithDimContext(), grep.WithColor("always"), grep.WithPassthru()},
+			in:      "a\nfoo",
+			out:     "\x1b[2m\x1b[Ka\x1b[m\x1b[K\n\x1b[01;31m\x1b[Kfoo\x1b[m\x1b[K\n",
+		},
+		{
+			name:    "WithDimContext/no-color",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithDimContext(), grep.WithContext(1)},
+			in:      "a\nfoo\nb",
+			out:     "a\nfoo\nb\n",
+		},
+		{
+			name:    "WithCount",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithCount()},
+			in:      "foo\nbar\nfoo bar\n",
+			out:     "2\n",
+		},
+		{
+			name:    "WithCount/no-final-newline",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithCount()},
+			in:      "foo\nbar\nfoo",
+			out:     "2\n",
+		},
+		{
+			name:    "WithCount+WithInvertMatch",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithCount(), grep.WithInvertMatch()},
+			in:      "foo\nbar\nbaz",
+			out:     "2\n",
+		},
+		{
+			name:    "WithCount+WithWordRegexp+WithLineRegexp",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithCount(), grep.WithWordRegexp(), grep.WithLineRegexp()},
+			in:      "foo\nfoo bar\nfoobar",
+			out:     "1\n",
+		},
+		{
+			name:    "WithCount/none",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithCount(), grep.WithContext(1)},
+			in:      "bar",
+			out:     "0\n",
+		},
+		{
+			name:    "WithMaxCount",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithMaxCount(2)},
+			in:      "foo 1\nbar\nfoo 2\nfoo 3",
+			out:     "foo 1\nfoo 2\n",
+		},
+		{
+			name:    "WithMaxCount+WithInvertMatch",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithMaxCount(1), grep.WithInvertMatch()},
+			in:      "foo 1\nbar\nbaz",
+			out:     "bar\n",
+		},
+		{
+			name:    "WithMaxCount/zero",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithMaxCount(0)},
+			in:      "foo",
+			out:     "",
+		},
+		{
+			name:    "WithMaxCount/negative",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithMaxCount(-1)},
+			in:      "foo\nfoo",
+			out:     "foo\nfoo\n",
+		},
+		{
+			name:    "WithMaxCount+WithAfterContext",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithMaxCount(1), grep.WithAfterContext(1)},
+			in:      "foo 1\nfoo 2\nbar",
+			out:     "foo 1\nfoo 2\n",
+		},
+		{
+			name:    "WithMaxCount+WithCount",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithMaxCount(2), grep.WithCount()},
+			in:      "foo\nfoo\nfoo",
+			out:     "2\n",
+		},
+		{
+			name:    "WithOnlyMatching",
+			pattern: "[0-9]+",
+			opts:    []grep.Option{grep.WithOnlyMatching()},
+			in:      "a 1 b 22 c 333\nnone\n4",
+			out:     "1\n22\n333\n4\n",
+		},
+		{
+			name:    "WithOnlyMatching+WithLineNumber",
+			pattern: "o+",
+			opts:    []grep.Option{grep.WithOnlyMatching(), grep.WithLineNumber()},
+			in:      "foo boo\nbar\nzoo",
+			out:     "1:oo\n1:oo\n3:oo\n",
+		},
+		{
+			name:    "WithOnlyMatching+WithByteOffset",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithOnlyMatching(), grep.WithByteOffset()},
+			in:      "bar\nä foo foo",
+			out:     "7:foo\n11:foo\n",
+		},
+		{
+			name:    "WithOnlyMatching/zero-width",
+			pattern: "x*",
+			opts:    []grep.Option{grep.WithOnlyMatching()},
+			in:      "abc\naxxbx",
+			out:     "xx\nx\n",
+		},
+		{
+			name:    "WithOnlyMatching+WithInvertMatch",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithOnlyMatching(), grep.WithInvertMatch()},
+			in:      "foo\nbar",
+			out:     "",
+		},
+		{
+			name:    "WithOnlyMatching+WithColor",
+			pattern: "o+",
+			opts:    []grep.Option{grep.WithOnlyMatching(), grep.WithColor("always")},
+			in:      "foo",
+			out:     "\x1b[01;31m\x1b[Koo\x1b[m\x1b[K\n",
+		},
+		{
+			name:    "WithOnlyMatching+WithContext",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithOnlyMatching(), grep.WithContext(1)},
+			in:      "a\nx foo\nb",
+			out:     "foo\n",
+		},
+		{
+			name:    "WithFilename",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithFilename()},
+			in:      "foo\nbar",
+			out:     "(standard input):foo\n",
+		},
+		{
+			name:    "WithLabel",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithLabel("stdin.txt"), grep.WithFilename(), grep.WithLineNumber()},
+			in:      "bar\nfoo",
+			out:     "stdin.txt:2:foo\n",
+		},
+		{
+			name:    "WithLabel/no-names",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithLabel("stdin.txt")},
+			in:      "foo",
+			out:     "foo\n",
+		},
+		{
+			name:    "WithFixedStrings",
+			pattern: ".*",
+			opts:    []grep.Option{grep.WithFixedStrings()},
+			in:      "abc\nlike .* this\n.",
+			out:     "like .* this\n",
+		},
+		{
+			name:    "WithFixedStrings/several",
+			pattern: "a+b\n(c)",
+			opts:    []grep.Option{grep.WithFixedStrings()},
+			in:      "aab\na+b\nc\n(c)",
+			out:     "a+b\n(c)\n",
+		},
+		{
+			name:    "WithFixedStrings+WithIgnoreCase",
+			pattern: "[X]",
+			opts:    []grep.Option{grep.WithFixedStrings(), grep.WithIgnoreCase()},
+			in:      "x\n[x]\n[X]",
+			out:     "[x]\n[X]\n",
+		},
+		{
+			name:    "WithFixedStrings+WithWordRegexp",
+			pattern: "a.b",
+			opts:    []grep.Option{grep.WithFixedStrings(), grep.WithWordRegexp()},
+			in:      "a.b c\nxa.b\naxb",
+			out:     "a.b c\n",
+		},
+		{
+			name:    "WithFixedStrings+WithLineRegexp",
+			pattern: "$1",
+			opts:    []grep.Option{grep.WithFixedStrings(), grep.WithLineRegexp()},
+			in:      "$1\n$10\n1",
+			out:     "$1\n",
+		},
+		{
+			name:    "WithFixedStrings+WithColor",
+			pattern: "o.",
+			opts:    []grep.Option{grep.WithFixedStrings(), grep.WithColor("always")},
+			in:      "foo o.k",
+			out:     "foo \x1b[01;31m\x1b[Ko.\x1b[m\x1b[Kk\n",
+		},
+		{
+			name:    "WithOnlyMatching/perl",
+			pattern: "a|ab",
+			opts:    []grep.Option{grep.WithOnlyMatching()},
+			in:      "abc",
+			out:     "a\n",
+		},
+		{
+			name:    "WithExtendedRegexp",
+			pattern: "a|ab",
+			opts:    []grep.Option{grep.WithExtendedRegexp(), grep.WithOnlyMatching()},
+			in:      "abc",
+			out:     "ab\n",
+		},
+		{
+			name:    "WithExtendedRegexp+WithColor",
+			pattern: "(x|xy)(z|yz)",
+			opts:    []grep.Option{grep.WithExtendedRegexp(), grep.WithColor("always")},
+			in:      "xyz",
+			out:     "\x1b[01;31m\x1b[Kxyz\x1b[m\x1b[K\n",
+		},
+		{
+			name:    "WithExtendedRegexp+WithIgnoreCase",
+			pattern: "^(foo)+$",
+			opts:    []grep.Option{grep.WithExtendedRegexp(), grep.WithIgnoreCase()},
+			in:      "FOOfoo\nfoox",
+			out:     "FOOfoo\n",
+		},
+		{
+			name:    "WithExtendedRegexp+WithFixedStrings",
+			pattern: "a|b",
+			opts:    []grep.Option{grep.WithExtendedRegexp(), grep.WithFixedStrings()},
+			in:      "a\na|b",
+			out:     "a|b\n",
+		},
+		{
+			name:    "WithFixedStrings+WithExtendedRegexp",
+			pattern: "a|b",
+			opts:    []grep.Option{grep.WithFixedStrings(), grep.WithExtendedRegexp()},
+			in:      "a\nc\na|b",
+			out:     "a\na|b\n",
+		},
+		{
+			name:    "WithPreserveEOL",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithPreserveEOL()},
+			in:      "a foo\r\nbar\nfoo\r\nb foo\nx foo",
+			out:     "a foo\r\nfoo\r\nb foo\nx foo",
+		},
+		{
+			name:    "WithPreserveEOL/final-newline",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithPreserveEOL()},
+			in:      "foo\r\nbar\nfoo\n",
+			out:     "foo\r\nfoo\n",
+		},
+		{
+			name:    "WithPreserveEOL/context",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithPreserveEOL(), grep.WithContext(1)},
+			in:      "a\r\nfoo\nb\r\nc\nd\r\nfoo",
+			out:     "a\r\nfoo\nb\r\n--\nd\r\nfoo",
+		},
+		{
+			name:    "WithPreserveEOL+WithNullData",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithPreserveEOL(), grep.WithNullData()},
+			in:      "foo\x00bar\x00foo",
+			out:     "foo\x00foo",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := strings.NewReader(tt.in)
+
+			out := grep.New(tt.pattern, tt.opts...).Read(in)
+
+			if body, err := ioutil.ReadAll(out); err != nil {
+				t.Fatalf("got err: %#v", err)
+			} else if string(body) != tt.out {
+				t.Fatalf("got %q want %q", string(body), tt.out)
+			}
+		})
+	}
+}
+
+func TestGrep_ReadNamed(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		opts    []grep.Option
+		in      []string
+		out     string
+	}{
+		{
+			name:    "plain",
+			pattern: "foo",
+			in:      []string{"foo\nbar", "baz\nfoo bar\n"},
+			out:     "file0:foo\nfile1:foo bar\n",
+		},
+		{
+			name:    "WithTotalOnly",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithTotalOnly()},
+			in:      []string{"foo\nbar\nfoo", "baz", "foo bar\n"},
+			out:     "3\n",
+		},
+		{
+			name:    "WithTotalOnly/none",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithTotalOnly()},
+			in:      []string{"bar", "baz"},
+			out:     "0\n",
+		},
+		{
+			name:    "WithTotalOnly+WithInvertMatch",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithTotalOnly(), grep.WithInvertMatch()},
+			in:      []string{"foo\nbar\nfoo", "baz", "foo bar\n"},
+			out:     "2\n",
+		},
+		{
+			name:    "WithMaxPerFile",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithMaxPerFile(2)},
+			in:      []string{"foo 1\nfoo 2\nfoo 3\nfoo 4", "bar", "foo 5\nbar\nfoo 6\nfoo 7\n"},
+			out:     "file0:foo 1\nfile0:foo 2\nfile2:foo 5\nfile2:foo 6\n",
+		},
+		{
+			name:    "WithMaxPerFile+WithTotalOnly",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithMaxPerFile(1), grep.WithTotalOnly()},
+			in:      []string{"foo\nfoo", "foo\nfoo\nfoo", "bar"},
+			out:     "2\n",
+		},
+		{
+			name:    "WithMaxPerFile+WithAfterContext",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithMaxPerFile(1), grep.WithAfterContext(1)},
+			in:      []string{"foo 1\nfoo 2\nfoo 3", "a\nfoo 4\nb\nfoo 5"},
+			out:     "file0:foo 1\nfile0-foo 2\n--\nfile1:foo 4\nfile1-b\n",
+		},
+		{
+			name:    "WithContext",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithContext(1)},
+			in:      []string{"a\nfoo", "foo\nb"},
+			out:     "file0-a\nfile0:foo\n--\nfile1:foo\nfile1-b\n",
+		},
+		{
+			name:    "WithCount",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithCount()},
+			in:      []string{"foo 1\nfoo 2", "bar", "foo 3"},
+			out:     "file0:2\nfile1:0\nfile2:1\n",
+		},
+		{
+			name:    "WithMaxCount",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithMaxCount(2)},
+			in:      []string{"foo 1", "bar\nfoo 2\nfoo 3", "foo 4"},
+			out:     "file0:foo 1\nfile1:foo 2\n",
+		},
+		{
+			name:    "interleaved",
+			pattern: "foo",
+			in:      []string{"foo 1\nbar\nfoo 2", "bar\nfoo 3\nbar\nfoo 4"},
+			out:     "file0:foo 1\nfile0:foo 2\nfile1:foo 3\nfile1:foo 4\n",
+		},
+		{
+			name:    "single",
+			pattern: "foo",
+			in:      []string{"foo\nbar"},
+			out:     "foo\n",
+		},
+		{
+			name:    "WithFilename",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithFilename()},
+			in:      []string{"foo\nbar"},
+			out:     "file0:foo\n",
+		},
+		{
+			name:    "WithFilename+WithLineNumber",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithFilename(), grep.WithLineNumber()},
+			in:      []string{"bar\nfoo", "foo"},
+			out:     "file0:2:foo\nfile1:1:foo\n",
+		},
+		{
+			name:    "WithNoFilename",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithNoFilename()},
+			in:      []string{"foo 1\nbar", "bar", "foo 2\nfoo 3"},
+			out:     "foo 1\nfoo 2\nfoo 3\n",
+		},
+		{
+			name:    "WithNoFilename+WithLineNumber",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithNoFilename(), grep.WithLineNumber()},
+			in:      []string{"foo", "bar\nfoo", "foo"},
+			out:     "1:foo\n2:foo\n1:foo\n",
+		},
+		{
+			name:    "WithFilename+WithNoFilename",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithFilename(), grep.WithNoFilename()},
+			in:      []string{"foo", "bar", "foo"},
+			out:     "foo\nfoo\n",
+		},
+		{
+			name:    "WithNoFilename+WithFilename",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithNoFilename(), grep.WithFilename()},
+			in:      []string{"foo", "bar", "foo"},
+			out:     "file0:foo\nfile2:foo\n",
+		},
+		{
+			name:    "WithLabel",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithLabel("-")},
+			in:      []string{"foo", "foo"},
+			out:     "file0:foo\nfile1:foo\n",
+		},
+		{
+			name:    "WithFilesWithMatches",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithFilesWithMatches(), grep.WithContext(1)},
+			in:      []string{"bar\nfoo 1\nfoo 2", "baz", "foo 3", ""},
+			out:     "file0\nfile2\n",
+		},
+		{
+			name:    "WithFilesWithMatches+WithInvertMatch",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithFilesWithMatches(), grep.WithInvertMatch()},
+			in:      []string{"foo", "foo\nbar"},
+			out:     "file1\n",
+		},
+		{
+			name:    "WithFilesWithoutMatch",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithFilesWithoutMatch(), grep.WithPassthru()},
+			in:      []string{"bar\nfoo 1", "baz", "foo 3", ""},
+			out:     "file1\nfile3\n",
+		},
+		{
+			name:    "WithFilesWithMatches+WithNullOutput",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithFilesWithMatches(), grep.WithNullOutput()},
+			in:      []string{"foo", "bar", "foo", "foo"},
+			out:     "file0\x00file2\x00file3\x00",
+		},
+		{
+			name:    "WithFilesWithoutMatch+WithNullOutput",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithFilesWithoutMatch(), grep.WithNullOutput()},
+			in:      []string{"bar", "foo", "baz", "qux"},
+			out:     "file0\x00file2\x00file3\x00",
+		},
+		{
+			name:    "WithNullOutput",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithNullOutput()},
+			in:      []string{"foo", "foo"},
+			out:     "file0:foo\nfile1:foo\n",
+		},
+		{
+			name:    "WithFilesWithFirstMatch",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithFilesWithFirstMatch()},
+			in:      []string{"bar\nfoo 1\nfoo 2", "baz", "foo 3\nfoo 4\n"},
+			out:     "file0:foo 1\nfile2:foo 3\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var in []grep.NamedReader
+			for i, body := range tt.in {
+				in = append(in, grep.NamedReader{Name: fmt.Sprintf("file%d", i), Reader: strings.NewReader(body)})
+			}
+
+			out := grep.New(tt.pattern, tt.opts...).ReadNamed(in...)
+
+			if body, err := ioutil.ReadAll(out); err != nil {
+				t.Fatalf("got err: %#v", err)
+			} else if string(body) != tt.out {
+				t.Fatalf("got %q want %q", string(body), tt.out)
+			}
+		})
+	}
+}
+
+func TestWithMaxLineLength(t *testing.T) {
+	line := `{"data":"` + strings.Repeat("x", 1<<20) + `","needle":true}`
+
+	tests := []struct {
+		name string
+		opts []grep.Option
+		err  error
+	}{
+		{name: "default", err: bufio.ErrTooLong},
+		{name: "larger", opts: []grep.Option{grep.WithMaxLineLength(2 << 20)}},
+		{name: "unlimited", opts: []grep.Option{grep.WithMaxLineLength(0)}},
+		{name: "smaller", opts: []grep.Option{grep.WithMaxLineLength(1 << 19)}, err: bufio.ErrTooLong},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := ioutil.ReadAll(grep.New("needle", tt.opts...).Read(strings.NewReader(line + "\n")))
+			if err != tt.err {
+				t.Fatalf("got err %v want %v", err, tt.err)
+			}
+			if tt.err == nil && string(out) != line+"\n" {
+				t.Errorf("got %d bytes want %d", len(out), len(line)+1)
+			}
+		})
+	}
+}
+
+func TestWithFuzzy_patternTooLong(t *testing.T) {
+	out := grep.New(strings.Repeat("a", 65), grep.WithFuzzy(1)).Read(strings.NewReader("a"))
+	if _, err := ioutil.ReadAll(out); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestWithExtendedRegexp_perlSyntax(t *testing.T) {
+	for _, pattern := range []string{`\d+`, `(?i)a`, `(a)\1`} {
+		out := grep.New(pattern, grep.WithExtendedRegexp()).Read(strings.NewReader("a"))
+		if _, err := ioutil.ReadAll(out); err == nil {
+			t.Errorf("%s: expected an error", pattern)
+		}
+	}
+}
+
+func BenchmarkGrep(b *testing.B) {
+	var in strings.Builder
+	for i := 0; i < 10000; i++ {
+		fmt.Fprintf(&in, "%d the quick brown fox jumps over the lazy dog\n", i)
+		if i%10 == 0 {
+			in.WriteString("ERROR something went wrong here\n")
+		}
+	}
+
+	for _, bench := range []struct {
+		name    string
+		pattern string
+		opts    []grep.Option
+	}{
+		{"literal", "ERROR", nil},
+		{"regexp", `ERR(OR)? \w+`, nil},
+		{"WithIgnoreCase", "error", []grep.Option{grep.WithIgnoreCase()}},
+		{"WithWordRegexp", "ERROR", []grep.Option{grep.WithWordRegexp()}},
+		{"WithInvertMatch", "ERROR", []grep.Option{grep.WithInvertMatch()}},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			b.SetBytes(int64(in.Len()))
+			for i := 0; i < b.N; i++ {
+				out := grep.New(bench.pattern, bench.opts...).Read(strings.NewReader(in.String()))
+				if _, err := io.Copy(ioutil.Discard, out); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestWithFilesWithFirstMatch_shortCircuit(t *testing.T) {
+	errRead := errors.New("read past first match")
+	out := grep.New("foo", grep.WithFilesWithFirstMatch()).ReadNamed(
+		grep.NamedReader{Name: "a", Reader: &failingReader{data: "bar\nfoo\n", err: errRead}},
+		grep.NamedReader{Reader: strings.NewReader("foo")},
+	)
+	if body, err := ioutil.ReadAll(out); err != nil {
+		t.Fatalf("got err: %v", err)
+	} else if want := "a:foo\n(standard input):foo\n"; string(body) != want {
+		t.Fatalf("got %q want %q", string(body), want)
+	}
+}
+
+func TestWithEntryStart_oneByteReads(t *testing.T) {
+	in := iotest.OneByteReader(strings.NewReader("[1] a\n  trace x\n[2] b\n[3] x\n  trace\n"))
+	out := grep.New("x", grep.WithEntryStart(`^\[`)).Read(in)
+	if body, err := ioutil.ReadAll(out); err != nil {
+		t.Fatalf("got err: %#v", err)
+	} else if want := "[1] a\n  trace x\n[3] x\n  trace\n"; string(body) != want {
+		t.Fatalf("got %q want %q", string(body), want)
+	}
+}
+
+func TestWithSummaryWriter(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []grep.Option
+		in      []string
+		out     string
+		summary string
+	}{
+		{
+			name:    "one",
+			in:      []string{"foo\nbar\nfoobar\n"},
+			out:     "foo\nfoobar\n",
+			summary: "matched=2 files=1 scanned_bytes=15\n",
+		},
+		{
+			name:    "several",
+			in:      []string{"foo\n", "bar\n", "foo foo"},
+			out:     "file0:foo\nfile2:foo foo\n",
+			summary: "matched=2 files=3 scanned_bytes=15\n",
+		},
+		{
+			name:    "no-match",
+			in:      []string{"bar\nbaz\n"},
+			out:     "",
+			summary: "matched=0 files=1 scanned_bytes=8\n",
+		},
+		{
+			name:    "no-input",
+			summary: "matched=0 files=0 scanned_bytes=0\n",
+		},
+		{
+			name:    "WithTotalOnly",
+			opts:    []grep.Option{grep.WithTotalOnly()},
+			in:      []string{"foo\n", "foo\n"},
+			out:     "2\n",
+			summary: "matched=2 files=2 scanned_bytes=8\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var inputs []grep.NamedReader
+			for i, in := range tt.in {
+				inputs = append(inputs, grep.NamedReader{Name: fmt.Sprint("file", i), Reader: strings.NewReader(in)})
+			}
+			var summary strings.Builder
+			opts := append([]grep.Option{grep.WithSummaryWriter(&summary)}, tt.opts...)
+
+			out := grep.New("foo", opts...).ReadNamed(inputs...)
+
+			if body, err := ioutil.ReadAll(out); err != nil {
+				t.Fatalf("got err: %#v", err)
+			} else if string(body) != tt.out {
+				t.Fatalf("got %q want %q", string(body), tt.out)
+			}
+			if summary.String() != tt.summary {
+				t.Fatalf("got summary %q want %q", summary.String(), tt.summary)
+			}
+		})
+	}
+}
+
+func TestWithSummaryWriter_error(t *testing.T) {
+	var summary strings.Builder
+	in := &failingReader{data: "foo\n", err: errors.New("read failed")}
+	out := grep.New("foo", grep.WithSummaryWriter(&summary)).Read(in)
+	if _, err := ioutil.ReadAll(out); err == nil {
+		t.Fatal("got nil error")
+	}
+	if summary.Len() > 0 {
+		t.Fatalf("got summary %q want none", summary.String())
+	}
+}
+
+func TestWithOutputBufferSize(t *testing.T) {
+	in := numberedLines(1000)
+
+	// a buffer a fraction of the output forces many flushes
+	out := grep.New("", grep.WithOutputBufferSize(16)).Read(strings.NewReader(in))
+	if body, err := ioutil.ReadAll(out); err != nil {
+		t.Fatalf("got err: %#v", err)
+	} else if string(body) != in {
+		t.Fatalf("got %d bytes want %d", len(body), len(in))
+	}
+}
+
+func TestWithOutputBufferSize_error(t *testing.T) {
+	errRead := errors.New("read failed")
+	in := &failingReader{data: "foo\nbar\nfoo\n", err: errRead}
+
+	out := grep.New("foo", grep.WithOutputBufferSize(4096)).Read(in)
+	body, err := ioutil.ReadAll(out)
+	if err != errRead {
+		t.Fatalf("got err %v want %v", err, errRead)
+	}
+	if string(body) != "foo\nfoo\n" {
+		t.Fatalf("got %q want %q", string(body), "foo\nfoo\n")
+	}
+}
+
+// numberedLines returns the lines "1\n" through "n\n".
+func TestWithMaxCount_stopsReading(t *testing.T) {
+	for _, n := range []int{0, 1, 10} {
+		t.Run(fmt.Sprint(n), func(t *testing.T) {
+			body := strings.Repeat("foo\n", 1<<20)
+			in := &readCounter{Reader: strings.NewReader(body)}
+
+			out, err := ioutil.ReadAll(grep.New("foo", grep.WithMaxCount(n)).Read(in))
+			if err != nil {
+				t.Fatalf("got err: %#v", err)
+			}
+			if got, want := string(out), strings.Repeat("foo\n", n); got != want {
+				t.Errorf("got %q want %q", got, want)
+			}
+			if in.n >= int64(len(body)) {
+				t.Errorf("read all %d bytes of input", in.n)
+			}
+			if n == 0 && in.n != 0 {
+				t.Errorf("read %d bytes of input want 0", in.n)
+			}
+		})
+	}
+}
+
+func TestWithFilesWithMatches_stopsReading(t *testing.T) {
+	body := "foo\n" + strings.Repeat("bar\n", 1<<20)
+	in := &readCounter{Reader: strings.NewReader(body)}
+
+	out, err := ioutil.ReadAll(grep.New("foo", grep.WithFilesWithMatches(), grep.WithLabel("in")).Read(in))
+	if err != nil {
+		t.Fatalf("got err: %#v", err)
+	}
+	if string(out) != "in\n" {
+		t.Errorf("got %q want %q", out, "in\n")
+	}
+	if in.n >= int64(len(body)) {
+		t.Errorf("read all %d bytes of input", in.n)
+	}
+}
+
+func TestWithQuiet(t *testing.T) {
+	body := "foo\n" + strings.Repeat("bar\n", 1<<20)
+	in := &readCounter{Reader: strings.NewReader(body)}
+
+	out, err := ioutil.ReadAll(grep.New("foo", grep.WithQuiet(), grep.WithContext(2)).Read(in))
+	if err != nil {
+		t.Fatalf("got err: %#v", err)
+	}
+	if len(out) != 0 {
+		t.Errorf("got %q want no output", out)
+	}
+	if in.n >= int64(len(body)) {
+		t.Errorf("read all %d bytes of input", in.n)
+	}
+}
+
+func TestWithLineBuffered(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  []grep.Option
+		lines []string
+		// output readable once each line has been written
+		out []string
+	}{
+		{
+			name:  "lines",
+			lines: []string{"foo 1\n", "bar\n", "foo 2\n"},
+			out:   []string{"foo 1\n", "", "foo 2\n"},
+		},
+		{
+			name:  "WithContext",
+			opts:  []grep.Option{grep.WithAfterContext(1)},
+			lines: []string{"foo 1\n", "a\n", "b\n", "foo 2\n"},
+			out:   []string{"foo 1\n", "a\n", "", "--\nfoo 2\n"},
+		},
+		{
+			name:  "WithContext+WithNullData",
+			opts:  []grep.Option{grep.WithAfterContext(1), grep.WithNullData()},
+			lines: []string{"foo 1\x00", "a\x00", "b\x00", "foo 2\x00"},
+			out:   []string{"foo 1\x00", "a\x00", "", "--\nfoo 2\x00"},
+		},
+		{
+			name:  "WithOnlyMatching",
+			opts:  []grep.Option{grep.WithOnlyMatching()},
+			lines: []string{"foo foo\n", "bar\n", "x foo\n"},
+			out:   []string{"foo\nfoo\n", "", "foo\n"},
+		},
+		{
+			name:  "WithFilesWithMatches",
+			opts:  []grep.Option{grep.WithFilesWithMatches(), grep.WithLabel("in")},
+			lines: []string{"bar\n", "foo\n"},
+			out:   []string{"", "in\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in, w := io.Pipe()
+			opts := append(tt.opts, grep.WithOutputBufferSize(1<<16), grep.WithLineBuffered())
+			out := grep.New("foo", opts...).Read(in)
+
+			for i, line := range tt.lines {
+				if _, err := io.WriteString(w, line); err != nil {
+					t.Fatal(err)
+				}
+				if tt.out[i] == "" {
+					continue
+				}
+				if got := readWithin(t, out, len(tt.out[i])); got != tt.out[i] {
+					t.Fatalf("after line %d: got %q want %q", i+1, got, tt.out[i])
+				}
+			}
+			w.Close()
+			ioutil.ReadAll(out)
+		})
+	}
+}
+
+func TestWithLineBuffered_WithCount(t *testing.T) {
+	in0, w0 := io.Pipe()
+	in1, w1 := io.Pipe()
+	opts := []grep.Option{grep.WithCount(), grep.WithOutputBufferSize(1 << 16), grep.WithLineBuffered()}
+	out := grep.New("foo", opts...).ReadNamed(
+		grep.NamedReader{Name: "a", Reader: in0},
+		grep.NamedReader{Name: "b", Reader: in1},
+	)
+
+	io.WriteString(w0, "foo\nfoo\n")
+	w0.Close()
+	// the count for a is readable before b has been read at all
+	if got := readWithin(t, out, 4); got != "a:2\n" {
+		t.Fatalf("got %q want %q", got, "a:2\n")
+	}
+	io.WriteString(w1, "bar\n")
+	w1.Close()
+	if got := readWithin(t, out, 4); got != "b:0\n" {
+		t.Fatalf("got %q want %q", got, "b:0\n")
+	}
+}
+
+// readWithin reads n bytes from r, failing t if they take too long to become
+// readable.
+func readWithin(t *testing.T, r io.Reader, n int) string {
+	t.Helper()
+	got := make(chan string, 1)
+	go func() {
+		buf := make([]byte, n)
+		n, _ := io.ReadFull(r, buf)
+		got <- string(buf[:n])
+	}()
+	select {
+	case s := <-got:
+		return s
+	case <-time.After(5 * time.Second):
+		t.Fatalf("%d bytes not readable", n)
+		return ""
+	}
+}
+
+// readCounter counts the bytes read from Reader.
+type readCounter struct {
+	io.Reader
+	n int64
+}
+
+func (r *readCounter) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+func numberedLines(n int) string {
+	var b strings.Builder
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&b, "%d\n", i)
+	}
+	return b.String()
+}
+
+func BenchmarkWithOutputBufferSize(b *testing.B) {
+	in := numberedLines(100000)
+
+	for _, size := range []int{0, 4096, 65536} {
+		b.Run(fmt.Sprint(size), func(b *testing.B) {
+			b.SetBytes(int64(len(in)))
+			for i := 0; i < b.N; i++ {
+				out := grep.New("", grep.WithOutputBufferSize(size)).Read(strings.NewReader(in))
+				if _, err := io.Copy(ioutil.Discard, out); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestWithSample(t *testing.T) {
+	var in strings.Builder
+	for i := 1; i <= 20; i++ {
+		fmt.Fprintf(&in, "line %d\n", i)
+	}
+
+	tests := []struct {
+		name string
+		opts []grep.Option
+		out  string
+	}{
+		{
+			name: "WithSample",
+			opts: []grep.Option{grep.WithSample(0.25)},
+			out:  "line 7\nline 8\nline 9\nline 13\nline 20\n",
+		},
+		{
+			name: "WithSample/all",
+			opts: []grep.Option{grep.WithSample(1)},
+			out:  in.String(),
+		},
+		{
+			name: "WithSample/none",
+			opts: []grep.Option{grep.WithSample(0)},
+			out:  "",
+		},
+		{
+			name: "WithSampleN",
+			opts: []grep.Option{grep.WithSampleN(4), grep.WithLineNumber()},
+			out:  "1:line 1\n3:line 3\n7:line 7\n8:line 8\n",
+		},
+		{
+			name: "WithSampleN/fewer",
+			opts: []grep.Option{grep.WithSampleN(50)},
+			out:  in.String(),
+		},
+		{
+			name: "WithSample+WithTotalOnly",
+			opts: []grep.Option{grep.WithSample(0.25), grep.WithTotalOnly()},
+			out:  "20\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]grep.Option{grep.WithRandSource(rand.NewSource(1))}, tt.opts...)
+
+			out := grep.New("line", opts...).Read(strings.NewReader(in.String()))
+
+			if body, err := ioutil.ReadAll(out); err != nil {
+				t.Fatalf("got err: %#v", err)
+			} else if string(body) != tt.out {
+				t.Fatalf("got %q want %q", string(body), tt.out)
+			}
+		})
+	}
+}
+
+func TestWithSampleN_exact(t *testing.T) {
+	in := numberedLines(1000)
+	for seed := int64(0); seed < 10; seed++ {
+		out := grep.New("", grep.WithSampleN(10), grep.WithRandSource(rand.NewSource(seed))).Read(strings.NewReader(in))
+		body, err := ioutil.ReadAll(out)
+		if err != nil {
+			t.Fatalf("got err: %#v", err)
+		}
+		if n := strings.Count(string(body), "\n"); n != 10 {
+			t.Fatalf("seed %d: got %d lines want 10", seed, n)
+		}
+	}
+}
+
+func TestWithSuffixSearch(t *testing.T) {
+	in := strings.Join([]string{
+		"GET /index.html status=200",
+		"GET /missing status=404",
+		"POST /api status=500 retry",
+		"status=200 GET /",
+		"héllo wörld",
+		"ab\nabab",
+		"abc abc",
+		"a\tb",
+		"",
+		"\xff\xfeinvalid utf8 status=200",
+	}, "\n")
+
+	for _, tt := range []struct {
+		pattern string
+		opts    []grep.Option
+	}{
+		{pattern: `status=\d+$`},
+		{pattern: `status=[45]\d\d$`},
+		{pattern: `^GET .* status=200$`},
+		{pattern: `status=200`},
+		{pattern: `w(ö|o)rld$`},
+		{pattern: `(ab)+$`},
+		{pattern: `\babc\b`},
+		{pattern: `^$`},
+		{pattern: `a\tb`},
+		{pattern: `(?m)^abc`},
+		{pattern: `\Aab\z`},
+		{pattern: `STATUS=\d+$`, opts: []grep.Option{grep.WithIgnoreCase()}},
+		{pattern: `abc`, opts: []grep.Option{grep.WithWordRegexp()}},
+		{pattern: `ab`, opts: []grep.Option{grep.WithLineRegexp()}},
+		{pattern: `status`, opts: []grep.Option{grep.WithInvertMatch()}},
+	} {
+		t.Run(tt.pattern, func(t *testing.T) {
+			want, err := ioutil.ReadAll(grep.New(tt.pattern, tt.opts...).Read(strings.NewReader(in)))
+			if err != nil {
+				t.Fatalf("got err: %#v", err)
+			}
+			opts := append([]grep.Option{grep.WithSuffixSearch()}, tt.opts...)
+			got, err := ioutil.ReadAll(grep.New(tt.pattern, opts...).Read(strings.NewReader(in)))
+			if err != nil {
+				t.Fatalf("got err: %#v", err)
+			}
+			if string(got) != string(want) {
+				t.Fatalf("got %q want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestGrep_Exec(t *testing.T) {
+	dir := tree(t, map[string]string{
+		"a.log": "ERROR 1\nok\n",
+		"b.log": "ok\nERROR 2\n",
+	})
+	defer os.RemoveAll(dir)
+	a, b := filepath.Join(dir, "a.log"), filepath.Join(dir, "b.log")
+
+	stdin, err := ioutil.TempFile(dir, "stdin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdin.Close()
+	if _, err := stdin.WriteString("ERROR 3\nok\n"); err != nil {
+		t.Fatal(err)
+	}
+	defer func(orig *os.File) { os.Stdin = orig }(os.Stdin)
+
+	tests := []struct {
+		name string
+		args []string
+		out  string
+	}{
+		{"one file", []string{a}, "ERROR 1\n"},
+		{"files", []string{a, b}, a + ":ERROR 1\n" + b + ":ERROR 2\n"},
+		{"stdin", []string{"-"}, "ERROR 3\n"},
+		{"no args", nil, "ERROR 3\n"},
+		{"file and stdin", []string{a, "-"}, a + ":ERROR 1\n(standard input):ERROR 3\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := stdin.Seek(0, io.SeekStart); err != nil {
+				t.Fatal(err)
+			}
+			os.Stdin = stdin
+
+			got, err := ioutil.ReadAll(grep.New("ERROR").Exec(tt.args))
+			if err != nil {
+				t.Fatalf("got err: %#v", err)
+			}
+			if string(got) != tt.out {
+				t.Fatalf("got %q want %q", got, tt.out)
+			}
+		})
+	}
+}
+
+func TestGrep_Exec_missingFile(t *testing.T) {
+	dir := tree(t, map[string]string{"a.log": "ERROR 1\n"})
+	defer os.RemoveAll(dir)
+	a, missing := filepath.Join(dir, "a.log"), filepath.Join(dir, "missing.log")
+
+	got, err := ioutil.ReadAll(grep.New("ERROR").Exec([]string{a, missing}))
+	if !os.IsNotExist(err) {
+		t.Fatalf("got err %#v want not exist", err)
+	}
+	if want := a + ":ERROR 1\n"; string(got) != want {
+		t.Fatalf("got %q want %q", got, want)
+	}
+}
+
+func TestWithNoMessages(t *testing.T) {
+	dir := tree(t, map[string]string{
+		"a.log":     "ERROR 1\n",
+		"b.log":     "ERROR 2\n",
+		"sub/c.log": "ERROR 3\n",
+	})
+	defer os.RemoveAll(dir)
+	a, b := filepath.Join(dir, "a.log"), filepath.Join(dir, "b.log")
+	missing, sub := filepath.Join(dir, "missing.log"), filepath.Join(dir, "sub")
+	// the directory can be opened but not read
+	args := []string{a, missing, sub, b}
+	want := a + ":ERROR 1\n" + b + ":ERROR 2\n"
+
+	got, err := ioutil.ReadAll(grep.New("ERROR", grep.WithNoMessages()).Exec(args))
+	if err != nil {
+		t.Fatalf("got err: %#v", err)
+	}
+	if string(got) != want {
+		t.Fatalf("got %q want %q", got, want)
+	}
+
+	got, err = ioutil.ReadAll(grep.New("ERROR").Exec(args))
+	if !os.IsNotExist(err) {
+		t.Fatalf("without WithNoMessages: got err %#v want not exist", err)
+	}
+	if string(got) != want {
+		t.Fatalf("without WithNoMessages: got %q want %q", got, want)
+	}
+}
+
+// manyPatterns returns n patterns, one per line, and input of which about
+// one line in ten matches one of them. The patterns are regular expressions
+// unless literal is set.
+func manyPatterns(n int, literal bool) (string, string) {
+	var patterns []string
+	for i := 0; i < n; i++ {
+		if literal {
+			patterns = append(patterns, fmt.Sprintf("E%03d: request failed", i))
+		} else {
+			patterns = append(patterns, fmt.Sprintf(`E%03d: \w+ failed`, i))
+		}
+	}
+	var in strings.Builder
+	for i := 0; i < 10000; i++ {
+		fmt.Fprintf(&in, "%d the quick brown fox jumps over the lazy dog\n", i)
+		if i%10 == 0 {
+			fmt.Fprintf(&in, "E%03d: request failed\n", i%n)
+		}
+	}
+	return strings.Join(patterns, "\n"), in.String()
+}
+
+func TestGrep_manyPatterns(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		literal bool
+		opts    []grep.Option
+	}{
+		{name: "regexp"},
+		{name: "literal", literal: true},
+		{name: "WithFixedStrings", literal: true, opts: []grep.Option{grep.WithFixedStrings()}},
+		{name: "WithIgnoreCase", opts: []grep.Option{grep.WithIgnoreCase()}},
+		{name: "WithOnlyMatching", opts: []grep.Option{grep.WithOnlyMatching()}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			patterns, in := manyPatterns(100, tt.literal)
+
+			// the same search, testing each pattern on its own
+			want, err := ioutil.ReadAll(grep.New(patterns, append(tt.opts, grep.WithoutCombining())...).Read(strings.NewReader(in)))
+			if err != nil {
+				t.Fatalf("got err: %#v", err)
+			}
+			got, err := ioutil.ReadAll(grep.New(patterns, tt.opts...).Read(strings.NewReader(in)))
+			if err != nil {
+				t.Fatalf("got err: %#v", err)
+			}
+			if n := strings.Count(string(want), "\n"); n != 1000 {
+				t.Fatalf("got %d lines want %d", n, 1000)
+			}
+			if string(got) != string(want) {
+				t.Fatalf("got %d bytes want %d", len(got), len(want))
+			}
+		})
+	}
+}
+
+func BenchmarkGrep_manyPatterns(b *testing.B) {
+	for _, bench := range []struct {
+		name    string
+		literal bool
+		opts    []grep.Option
+	}{
+		{name: "regexp/combined"},
+		{name: "regexp/each", opts: []grep.Option{grep.WithoutCombining()}},
+		{name: "literal/combined", literal: true},
+		{name: "literal/each", literal: true, opts: []grep.Option{grep.WithoutCombining()}},
+		{name: "WithFixedStrings/combined", literal: true, opts: []grep.Option{grep.WithFixedStrings()}},
+		{name: "WithFixedStrings/each", literal: true, opts: []grep.Option{grep.WithFixedStrings(), grep.WithoutCombining()}},
+	} {
+		patterns, in := manyPatterns(100, bench.literal)
+		b.Run(bench.name, func(b *testing.B) {
+			b.SetBytes(int64(len(in)))
+			for i := 0; i < b.N; i++ {
+				out := grep.New(patterns, bench.opts...).Read(strings.NewReader(in))
+				if _, err := io.Copy(ioutil.Discard, out); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestGrep_overlappingPatterns(t *testing.T) {
+	// lines are printed from the scanner's buffer, which the next line
+	// overwrites, so each must be written out whole before it is
+	var in, want, wantOnly strings.Builder
+	for i := 0; i < 5000; i++ {
+		fmt.Fprintf(&in, "%d abc %d\n", i, i)
+		fmt.Fprintf(&want, "%d abc %d\n", i, i)
+		wantOnly.WriteString("abc \n")
+		fmt.Fprintf(&in, "%d xyz %d\n", i, i)
+	}
+	patterns := "ab\nbc\nabc\nb\nc ?"
+
+	for _, tt := range []struct {
+		name string
+		opts []grep.Option
+		want string
+	}{
+		{"lines", nil, want.String()},
+		{"WithOnlyMatching", []grep.Option{grep.WithOnlyMatching()}, wantOnly.String()},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			out := grep.New(patterns, tt.opts...).Read(iotest.HalfReader(strings.NewReader(in.String())))
+			got, err := ioutil.ReadAll(out)
+			if err != nil {
+				t.Fatalf("got err: %#v", err)
+			}
+			if string(got) != tt.want {
+				t.Fatalf("got %d bytes want %d", len(got), len(tt.want))
+			}
+		})
+	}
+}
+
+func TestWithBinaryFiles_unknown(t *testing.T) {
+	_, err := ioutil.ReadAll(grep.New("ERROR", grep.WithBinaryFiles("data")).Read(strings.NewReader("ERROR\n")))
+	if err == nil || !strings.Contains(err.Error(), "unknown binary-files type") {
+		t.Fatalf("got err %v want an unknown binary-files type", err)
+	}
+}
+
+func TestGrep_Validate(t *testing.T) {
+	patterns, err := ioutil.TempFile(t.TempDir(), "patterns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := patterns.WriteString("ok\n(\n"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := patterns.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	defer patterns.Close()
+
+	tests := []struct {
+		name    string
+		pattern string
+		opts    []grep.Option
+		// the pattern the error names, if any
+		bad string
+	}{
+		{name: "valid", pattern: "a|b"},
+		{name: "pattern", pattern: "[", bad: "["},
+		{name: "pattern-line", pattern: "ok\na[", bad: "a["},
+		{name: "WithRegexps", opts: []grep.Option{grep.WithRegexps("ok", "x{2,1}")}, bad: "x{2,1}"},
+		{name: "WithFiles", opts: []grep.Option{grep.WithFiles(patterns)}, bad: "("},
+		{name: "WithBasicRegexp", pattern: `a\`, opts: []grep.Option{grep.WithBasicRegexp()}, bad: `a\`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := grep.New(tt.pattern, tt.opts...).Validate()
+			if tt.bad == "" {
+				if err != nil {
+					t.Fatalf("got err %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("got no error")
+			}
+			if want := fmt.Sprintf("grep: pattern %q: ", tt.bad); !strings.HasPrefix(err.Error(), want) {
+				t.Errorf("got err %q want it to start %q", err, want)
+			}
+		})
+	}
+}
+
+func TestGrep_Validate_thenSearch(t *testing.T) {
+	patterns, err := ioutil.TempFile(t.TempDir(), "patterns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := patterns.WriteString("ERROR\n"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := patterns.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	defer patterns.Close()
+
+	cmd := grep.New("", grep.WithFiles(patterns))
+	if err := cmd.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	// the patterns read by Validate are kept for the search
+	out, err := ioutil.ReadAll(cmd.Read(strings.NewReader("INFO\nERROR\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(out), "ERROR\n"; got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+}
+
+func TestPatternError(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		opts    []grep.Option
+		bad     string
+	}{
+		{name: "pattern", pattern: "ok\n[", bad: "["},
+		{name: "WithRegexps", opts: []grep.Option{grep.WithRegexps("(")}, bad: "("},
+		{name: "WithBasicRegexp", pattern: `\(a\)\1`, opts: []grep.Option{grep.WithBasicRegexp()}, bad: `\(a\)\1`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ioutil.ReadAll(grep.New(tt.pattern, tt.opts...).Read(strings.NewReader("a\n")))
+			var patternErr *grep.PatternError
+			if !errors.As(err, &patternErr) {
+				t.Fatalf("got err %v want a *grep.PatternError", err)
+			}
+			if patternErr.Pattern != tt.bad {
+				t.Errorf("got pattern %q want %q", patternErr.Pattern, tt.bad)
+			}
+			if patternErr.Err == nil {
+				t.Error("got no underlying error")
+			}
+		})
+	}
+}
+
+func TestOptionConflictError(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []grep.Option
+		want [2]string
+	}{
+		{
+			name: "WithFixedStrings+WithBasicRegexp",
+			opts: []grep.Option{grep.WithFixedStrings(), grep.WithBasicRegexp()},
+			want: [2]string{"WithFixedStrings", "WithBasicRegexp"},
+		},
+		{
+			name: "WithBasicRegexp+WithFixedStrings",
+			opts: []grep.Option{grep.WithBasicRegexp(), grep.WithFixedStrings()},
+			want: [2]string{"WithBasicRegexp", "WithFixedStrings"},
+		},
+		{
+			name: "WithExtendedRegexp-between",
+			opts: []grep.Option{grep.WithBasicRegexp(), grep.WithExtendedRegexp(), grep.WithFixedStrings()},
+			want: [2]string{"WithBasicRegexp", "WithFixedStrings"},
+		},
+		{
+			name: "WithTreeOutput+WithOnlyMatching",
+			opts: []grep.Option{grep.WithTreeOutput(), grep.WithOnlyMatching()},
+			want: [2]string{"WithTreeOutput", "WithOnlyMatching"},
+		},
+		{
+			name: "WithTreeOutput+WithCount",
+			opts: []grep.Option{grep.WithTreeOutput(), grep.WithCount()},
+			want: [2]string{"WithTreeOutput", "WithCount"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := grep.New("a", tt.opts...).Validate()
+			var conflict *grep.OptionConflictError
+			if !errors.As(err, &conflict) {
+				t.Fatalf("got err %v want a *grep.OptionConflictError", err)
+			}
+			if conflict.Options != tt.want {
+				t.Errorf("got %q want %q", conflict.Options, tt.want)
+			}
+		})
+	}
+}
+
+func TestGrep_patternWithFiles(t *testing.T) {
+	patterns, err := ioutil.TempFile(t.TempDir(), "patterns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := patterns.WriteString("foo\n"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := patterns.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	defer patterns.Close()
+
+	out, err := ioutil.ReadAll(grep.New("baz", grep.WithFiles(patterns), grep.WithRegexps("qux")).Read(strings.NewReader("foo\nbar\nbaz\nqux\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(out), "foo\nbaz\nqux\n"; got != want {
+		t.Errorf("got %q want %q", got, want)
 	}
 }
@@ -0,0 +1,66 @@
+package grep
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+)
+
+// Highlighter highlights the matches of a regular expression in its input
+// with the ANSI escape sequences of WithColor, so that a stage of a pipeline
+// can color the output of another without that stage highlighting itself.
+type Highlighter struct {
+	regexp  *regexp.Regexp
+	enabled bool
+}
+
+// NewHighlighter returns a Highlighter for the matches of re. Unless enabled,
+// as when output is not going to a terminal, it passes its input through
+// unchanged.
+func NewHighlighter(re *regexp.Regexp, enabled bool) *Highlighter {
+	return &Highlighter{regexp: re, enabled: enabled}
+}
+
+// Read returns input with every non-empty match highlighted. Input is read a
+// line at a time, so each line is readable once it is highlighted, and matches
+// do not span lines.
+func (h *Highlighter) Read(input io.Reader) io.Reader {
+	if !h.enabled {
+		return input
+	}
+	r, w := newPipe()
+	go func() {
+		w.CloseWithError(h.highlight(w, input))
+	}()
+	return r
+}
+
+func (h *Highlighter) highlight(w io.Writer, input io.Reader) error {
+	br := bufio.NewReader(input)
+	var out []byte
+	for {
+		line, err := br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		atEOF := err == io.EOF
+		text := line
+		if n := len(text); n > 0 && text[n-1] == '\n' {
+			text = text[:n-1]
+		}
+		var spans [][]int
+		for _, span := range h.regexp.FindAllIndex(text, -1) {
+			if span[0] < span[1] {
+				spans = append(spans, span)
+			}
+		}
+		out = appendDisplayed(out[:0], text, spans, false)
+		out = append(out, line[len(text):]...)
+		if _, err := w.Write(out); err != nil {
+			return err
+		}
+		if atEOF {
+			return nil
+		}
+	}
+}
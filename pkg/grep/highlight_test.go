@@ -0,0 +1,67 @@
+package grep_test
+
+import (
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/kevin-cantwell/usrbin/pkg/grep"
+)
+
+func TestHighlighter(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		enabled bool
+		in      string
+		out     string
+	}{
+		{
+			name:    "matches",
+			expr:    "o+",
+			enabled: true,
+			in:      "foo boo\nbar\n",
+			out:     "f\x1b[01;31m\x1b[Koo\x1b[m\x1b[K b\x1b[01;31m\x1b[Koo\x1b[m\x1b[K\nbar\n",
+		},
+		{
+			name:    "no-final-newline",
+			expr:    "r$",
+			enabled: true,
+			in:      "bar\nbaz",
+			out:     "ba\x1b[01;31m\x1b[Kr\x1b[m\x1b[K\nbaz",
+		},
+		{
+			name:    "empty-matches",
+			expr:    "x*",
+			enabled: true,
+			in:      "axb\n",
+			out:     "a\x1b[01;31m\x1b[Kx\x1b[m\x1b[Kb\n",
+		},
+		{
+			name:    "long-line",
+			expr:    "abcd",
+			enabled: true,
+			in:      strings.Repeat("x", 4094) + "abcd\n",
+			out:     strings.Repeat("x", 4094) + "\x1b[01;31m\x1b[Kabcd\x1b[m\x1b[K\n",
+		},
+		{
+			name: "disabled",
+			expr: "o+",
+			in:   "foo\nbar\n",
+			out:  "foo\nbar\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := grep.NewHighlighter(regexp.MustCompile(tt.expr), tt.enabled)
+			out, err := ioutil.ReadAll(h.Read(strings.NewReader(tt.in)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(out) != tt.out {
+				t.Errorf("got %q want %q", out, tt.out)
+			}
+		})
+	}
+}
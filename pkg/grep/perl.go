@@ -0,0 +1,82 @@
+package grep
+
+import "strings"
+
+// perlGroups are the Perl group constructs that RE2 lacks, longest first.
+var perlGroups = []struct{ construct, feature string }{
+	{"(?<=", "lookbehind"},
+	{"(?<!", "negative lookbehind"},
+	{"(?=", "lookahead"},
+	{"(?!", "negative lookahead"},
+	{"(?>", "atomic group"},
+}
+
+// unsupportedPerl returns the first construct in expr, a Perl regular
+// expression, that RE2 does not support, or nil if there is none. Escaped
+// characters, character classes and \Q...\E quotes are skipped, since
+// constructs in them are literal.
+func unsupportedPerl(expr string) *UnsupportedSyntaxError {
+	for i := 0; i < len(expr); i++ {
+		switch c := expr[i]; c {
+		case '\\':
+			if i+1 == len(expr) {
+				return nil
+			}
+			i++
+			switch c := expr[i]; {
+			case '1' <= c && c <= '9':
+				return &UnsupportedSyntaxError{Feature: "backreference", Construct: expr[i-1 : i+1]}
+			case c == 'k' && i+1 < len(expr) && strings.IndexByte("<{'", expr[i+1]) >= 0:
+				return &UnsupportedSyntaxError{Feature: "named backreference", Construct: expr[i-1 : i+2]}
+			case c == 'Q':
+				end := strings.Index(expr[i:], `\E`)
+				if end < 0 {
+					return nil
+				}
+				i += end + 1
+			}
+		case '[':
+			i += classLength(expr[i:]) - 1
+		case '(':
+			for _, g := range perlGroups {
+				if strings.HasPrefix(expr[i:], g.construct) {
+					return &UnsupportedSyntaxError{Feature: g.feature, Construct: g.construct}
+				}
+			}
+			if strings.HasPrefix(expr[i:], "(?") {
+				i++
+			}
+		case '*', '+', '?', '}':
+			if i+1 < len(expr) && expr[i+1] == '+' {
+				return &UnsupportedSyntaxError{Feature: "possessive quantifier", Construct: expr[i : i+2]}
+			}
+		}
+	}
+	return nil
+}
+
+// classLength returns the length of the character class at the start of expr,
+// or of all of expr if the class is not closed.
+func classLength(expr string) int {
+	i := 1
+	if i < len(expr) && expr[i] == '^' {
+		i++
+	}
+	if i < len(expr) && expr[i] == ']' {
+		// a leading ] is literal
+		i++
+	}
+	for ; i < len(expr); i++ {
+		switch {
+		case expr[i] == ']':
+			return i + 1
+		case expr[i] == '\\':
+			i++
+		case strings.HasPrefix(expr[i:], "[:"):
+			if end := strings.Index(expr[i+2:], ":]"); end >= 0 {
+				i += 2 + end + 1
+			}
+		}
+	}
+	return len(expr)
+}
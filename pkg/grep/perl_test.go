@@ -0,0 +1,80 @@
+package grep_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/kevin-cantwell/usrbin/pkg/grep"
+)
+
+func TestWithPerlRegexp_unsupported(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		// the unsupported construct, if any
+		construct, feature string
+	}{
+		{name: "lookahead", pattern: "foo(?=bar)", construct: "(?=", feature: "lookahead"},
+		{name: "negative-lookahead", pattern: "foo(?!bar)", construct: "(?!", feature: "negative lookahead"},
+		{name: "lookbehind", pattern: "(?<=foo)bar", construct: "(?<=", feature: "lookbehind"},
+		{name: "negative-lookbehind", pattern: "(?<!foo)bar", construct: "(?<!", feature: "negative lookbehind"},
+		{name: "atomic-group", pattern: "(?>a+)b", construct: "(?>", feature: "atomic group"},
+		{name: "backreference", pattern: `(a)\1`, construct: `\1`, feature: "backreference"},
+		{name: "named-backreference", pattern: `(?P<x>a)\k<x>`, construct: `\k<`, feature: "named backreference"},
+		{name: "possessive", pattern: "a++b", construct: "++", feature: "possessive quantifier"},
+		{name: "after-escaped-backslash", pattern: `\\(?=a)`, construct: "(?=", feature: "lookahead"},
+		{name: "in-class", pattern: `[(?=]\1`, construct: `\1`, feature: "backreference"},
+		{name: "in-quote", pattern: `\Q(?=\E(`},
+		{name: "other-error", pattern: "a["},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := grep.New(tt.pattern, grep.WithPerlRegexp()).Validate()
+			var patternErr *grep.PatternError
+			if !errors.As(err, &patternErr) || patternErr.Pattern != tt.pattern {
+				t.Fatalf("got err %v want a *grep.PatternError for %q", err, tt.pattern)
+			}
+			var unsupported *grep.UnsupportedSyntaxError
+			if !errors.As(err, &unsupported) {
+				if tt.construct != "" {
+					t.Fatalf("got err %v want a *grep.UnsupportedSyntaxError", err)
+				}
+				return
+			}
+			if tt.construct == "" {
+				t.Fatalf("got err %v want no *grep.UnsupportedSyntaxError", err)
+			}
+			if unsupported.Construct != tt.construct || unsupported.Feature != tt.feature {
+				t.Errorf("got %s %s want %s %s", unsupported.Feature, unsupported.Construct, tt.feature, tt.construct)
+			}
+			if !strings.Contains(err.Error(), tt.construct) {
+				t.Errorf("got err %q want it to name %s", err, tt.construct)
+			}
+		})
+	}
+}
+
+func TestWithPerlRegexp(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []grep.Option
+		out  string
+	}{
+		{name: "alone", opts: []grep.Option{grep.WithPerlRegexp()}, out: "12\n"},
+		{name: "applied-last", opts: []grep.Option{grep.WithFixedStrings(), grep.WithPerlRegexp()}, out: "12\n"},
+		{name: "WithFixedStrings-applied-last", opts: []grep.Option{grep.WithPerlRegexp(), grep.WithFixedStrings()}, out: "\\d+\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := ioutil.ReadAll(grep.New(`\d+`, tt.opts...).Read(strings.NewReader("a\n12\n\\d+\n")))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(out) != tt.out {
+				t.Errorf("got %q want %q", out, tt.out)
+			}
+		})
+	}
+}
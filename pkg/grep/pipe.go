@@ -0,0 +1,170 @@
+package grep
+
+import (
+	"io"
+	"sync"
+)
+
+// pipe is io.Pipe, except that its reading end is also an io.WriterTo, which
+// hands each write straight to its destination instead of copying it through
+// a buffer, so io.Copy from the output of a search costs no more than the
+// writes themselves.
+type pipe struct {
+	// serializes writes
+	wrMu sync.Mutex
+	wrCh chan []byte
+	rdCh chan int
+
+	once sync.Once
+	// closed once either end is closed
+	done chan struct{}
+	mu   sync.Mutex
+	// the errors the reading and writing ends were closed with
+	rerr, werr error
+}
+
+// pipeReader is the reading end of a pipe.
+type pipeReader struct {
+	p *pipe
+}
+
+// pipeWriter is the writing end of a pipe.
+type pipeWriter struct {
+	p *pipe
+}
+
+func newPipe() (*pipeReader, *pipeWriter) {
+	p := &pipe{
+		wrCh: make(chan []byte),
+		rdCh: make(chan int),
+		done: make(chan struct{}),
+	}
+	return &pipeReader{p}, &pipeWriter{p}
+}
+
+func (r *pipeReader) Read(b []byte) (int, error) {
+	p := r.p
+	select {
+	case <-p.done:
+		return 0, p.readCloseError()
+	default:
+	}
+
+	select {
+	case bw := <-p.wrCh:
+		n := copy(b, bw)
+		p.rdCh <- n
+		return n, nil
+	case <-p.done:
+		return 0, p.readCloseError()
+	}
+}
+
+// WriteTo writes everything written to the pipe to w until the writing end is
+// closed, failing the writes with any error from w.
+func (r *pipeReader) WriteTo(w io.Writer) (int64, error) {
+	p := r.p
+	var n int64
+	for {
+		select {
+		case bw := <-p.wrCh:
+			nw, err := w.Write(bw)
+			n += int64(nw)
+			if err == nil && nw < len(bw) {
+				err = io.ErrShortWrite
+			}
+			if err != nil {
+				// close before replying, so the write sees the error
+				p.closeRead(err)
+				p.rdCh <- nw
+				return n, err
+			}
+			p.rdCh <- nw
+		case <-p.done:
+			err := p.readCloseError()
+			if err == io.EOF {
+				err = nil
+			}
+			return n, err
+		}
+	}
+}
+
+// Close closes the reading end, failing further writes with
+// io.ErrClosedPipe.
+func (r *pipeReader) Close() error {
+	r.p.closeRead(nil)
+	return nil
+}
+
+func (w *pipeWriter) Write(b []byte) (int, error) {
+	p := w.p
+	select {
+	case <-p.done:
+		return 0, p.writeCloseError()
+	default:
+		p.wrMu.Lock()
+		defer p.wrMu.Unlock()
+	}
+
+	var n int
+	for once := true; once || len(b) > 0; once = false {
+		select {
+		case p.wrCh <- b:
+			nw := <-p.rdCh
+			b = b[nw:]
+			n += nw
+		case <-p.done:
+			return n, p.writeCloseError()
+		}
+	}
+	return n, nil
+}
+
+// CloseWithError closes the writing end, so that reads fail with err once
+// everything written has been read, or return io.EOF if err is nil.
+func (w *pipeWriter) CloseWithError(err error) error {
+	if err == nil {
+		err = io.EOF
+	}
+	p := w.p
+	p.mu.Lock()
+	if p.werr == nil {
+		p.werr = err
+	}
+	p.mu.Unlock()
+	p.once.Do(func() { close(p.done) })
+	return nil
+}
+
+func (p *pipe) closeRead(err error) {
+	if err == nil {
+		err = io.ErrClosedPipe
+	}
+	p.mu.Lock()
+	if p.rerr == nil {
+		p.rerr = err
+	}
+	p.mu.Unlock()
+	p.once.Do(func() { close(p.done) })
+}
+
+// readCloseError is the error reads fail with once the pipe is closed.
+func (p *pipe) readCloseError() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rerr == nil && p.werr != nil {
+		return p.werr
+	}
+	return io.ErrClosedPipe
+}
+
+// writeCloseError is the error writes fail with once the pipe is closed.
+func (p *pipe) writeCloseError() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.werr == nil && p.rerr != nil {
+		return p.rerr
+	}
+	return io.ErrClosedPipe
+}
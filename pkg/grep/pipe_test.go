@@ -0,0 +1,98 @@
+package grep_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/kevin-cantwell/usrbin/pkg/grep"
+)
+
+// onlyReader hides any io.WriterTo method of Reader.
+type onlyReader struct {
+	io.Reader
+}
+
+// onlyWriter hides any io.ReaderFrom method of Writer.
+type onlyWriter struct {
+	io.Writer
+}
+
+func TestGrep_WriteTo(t *testing.T) {
+	in := strings.Repeat("a\nfoo 1\nb\nc\nd\nfoo 2\n", 1000)
+	opts := []grep.Option{grep.WithLineNumber(), grep.WithContext(1), grep.WithFilename(), grep.WithLabel("in")}
+
+	want, err := ioutil.ReadAll(onlyReader{grep.New("foo", opts...).Read(strings.NewReader(in))})
+	if err != nil {
+		t.Fatalf("got err: %#v", err)
+	}
+
+	out := grep.New("foo", opts...).Read(strings.NewReader(in))
+	wt, ok := out.(io.WriterTo)
+	if !ok {
+		t.Fatalf("%T is not an io.WriterTo", out)
+	}
+	var got bytes.Buffer
+	n, err := wt.WriteTo(&got)
+	if err != nil {
+		t.Fatalf("got err: %#v", err)
+	}
+	if n != int64(got.Len()) {
+		t.Fatalf("got n %d want %d", n, got.Len())
+	}
+	if got.String() != string(want) {
+		t.Fatalf("got %d bytes want %d", got.Len(), len(want))
+	}
+}
+
+func TestGrep_WriteTo_writeError(t *testing.T) {
+	want := errors.New("disk full")
+	in := strings.Repeat("foo\n", 10000)
+	out := grep.New("foo").Read(strings.NewReader(in))
+
+	// a failed write stops the search
+	n, err := io.Copy(failingWriter{want}, out)
+	if err != want {
+		t.Fatalf("got err %#v want %#v", err, want)
+	}
+	if n != 0 {
+		t.Fatalf("got n %d want %d", n, 0)
+	}
+	if _, err := out.Read(make([]byte, 1)); err == nil {
+		t.Fatal("read after a failed WriteTo succeeded")
+	}
+}
+
+func BenchmarkGrep_WriteTo(b *testing.B) {
+	var in strings.Builder
+	for i := 0; i < 10000; i++ {
+		fmt.Fprintf(&in, "%d the quick brown fox jumps over the lazy dog\n", i)
+		if i%10 == 0 {
+			in.WriteString("ERROR something went wrong here\n")
+		}
+	}
+
+	for _, bench := range []struct {
+		name string
+		wrap func(io.Reader) io.Reader
+	}{
+		{"WriteTo", func(r io.Reader) io.Reader { return r }},
+		{"Read", func(r io.Reader) io.Reader { return onlyReader{r} }},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(in.Len()))
+			for i := 0; i < b.N; i++ {
+				out := grep.New("ERROR", grep.WithLineNumber()).Read(strings.NewReader(in.String()))
+				// without WriteTo, io.Copy needs a buffer of its own
+				if _, err := io.Copy(onlyWriter{ioutil.Discard}, bench.wrap(out)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
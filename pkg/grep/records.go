@@ -0,0 +1,167 @@
+package grep
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"regexp"
+)
+
+// scanner splits input into records, keeping track of where each starts.
+type scanner struct {
+	*bufio.Scanner
+	// offset of the current record
+	offset int64
+	// bytes of input consumed
+	consumed int64
+	// terminator of the current record as it appeared in the input, empty if
+	// the record ended the input unterminated
+	eol []byte
+	// a NUL byte has been read, if WithBinaryFiles asks for binary input to
+	// be told apart, and how much of the data to split has been checked
+	binary  bool
+	sniffed int
+}
+
+// newScanner returns a scanner splitting input into the records patterns are
+// matched against: lines, unless an Option says otherwise.
+func (ms *matchAll) newScanner(input io.Reader) *scanner {
+	split := bufio.ScanLines
+	switch {
+	case ms.opts.z:
+		split = scanNull
+	case ms.opts.paragraph:
+		split = scanParagraphs
+	case ms.entryStart != nil:
+		split = scanEntries(ms.entryStart)
+	case ms.opts.U:
+		split = scanLinesKeepCR
+	}
+
+	// NULs end records under -z, so do not make input binary
+	sniff := ms.opts.binaryFiles != "" && ms.opts.binaryFiles != "text" && !ms.opts.z
+
+	s := &scanner{Scanner: bufio.NewScanner(input)}
+	if max := ms.opts.maxLineLength; max != bufio.MaxScanTokenSize {
+		if max <= 0 {
+			max = int(^uint(0) >> 1)
+		}
+		s.Buffer(nil, max)
+	}
+	s.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		// like grep, look for NULs in the data read so far rather than record
+		// by record, so that records read along with one are binary too
+		if sniff && !s.binary && s.sniffed < len(data) {
+			s.binary = bytes.IndexByte(data[s.sniffed:], 0) >= 0
+			s.sniffed = len(data)
+		}
+		advance, token, err := split(data, atEOF)
+		if s.sniffed -= advance; s.sniffed < 0 {
+			s.sniffed = 0
+		}
+		if token != nil {
+			// every SplitFunc here returns a slice of data
+			start := cap(data) - cap(token)
+			s.offset = s.consumed + int64(start)
+			s.eol = data[start+len(token) : advance]
+			if i := bytes.IndexByte(s.eol, '\n'); i >= 0 {
+				// paragraphs are followed by any number of empty lines
+				s.eol = s.eol[:i+1]
+			}
+		}
+		s.consumed += int64(advance)
+		return advance, token, err
+	})
+	return s
+}
+
+// scanLinesKeepCR is bufio.ScanLines, except that a carriage return before
+// the newline is kept as part of the line.
+func scanLinesKeepCR(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	// request more data
+	return 0, nil, nil
+}
+
+// scanNull is a bufio.SplitFunc returning each NUL-terminated record, without
+// its terminator. A final record need not be terminated.
+func scanNull(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	// request more data
+	return 0, nil, nil
+}
+
+// scanParagraphs is a bufio.SplitFunc returning each run of non-empty lines,
+// without its final newline. Any number of empty lines separate paragraphs and
+// empty lines at the start or end of input are dropped.
+func scanParagraphs(data []byte, atEOF bool) (int, []byte, error) {
+	start := 0
+	for start < len(data) && data[start] == '\n' {
+		start++
+	}
+
+	if i := bytes.Index(data[start:], []byte("\n\n")); i >= 0 {
+		end := start + i
+		return end + 2, data[start:end], nil
+	}
+
+	if atEOF {
+		if start == len(data) {
+			return len(data), nil, nil
+		}
+		return len(data), bytes.TrimSuffix(data[start:], []byte("\n")), nil
+	}
+
+	// request more data
+	return start, nil, nil
+}
+
+// scanEntries returns a bufio.SplitFunc that groups lines into entries, each
+// starting at a line matched by start and running up to the next such line,
+// such as a log line followed by its stack trace. An entry is returned without
+// its final newline. Lines before the first start line form an entry of their
+// own.
+func scanEntries(start *regexp.Regexp) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if len(data) == 0 {
+			return 0, nil, nil
+		}
+
+		// the first line belongs to the entry whether or not it matches start
+		end := bytes.IndexByte(data, '\n')
+		for end >= 0 && end+1 < len(data) {
+			next := data[end+1:]
+			n := bytes.IndexByte(next, '\n')
+			if n < 0 {
+				if !atEOF {
+					// request more data to see the whole next line
+					return 0, nil, nil
+				}
+				n = len(next)
+			}
+			if start.Match(next[:n]) {
+				return end + 1, data[:end], nil
+			}
+			if n == len(next) {
+				break
+			}
+			end += 1 + n
+		}
+
+		if atEOF {
+			return len(data), bytes.TrimSuffix(data, []byte("\n")), nil
+		}
+		// request more data
+		return 0, nil, nil
+	}
+}
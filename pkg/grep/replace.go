@@ -0,0 +1,41 @@
+package grep
+
+// replace appends to dst line with every match of m replaced by template,
+// expanded as by regexp.Expand. Matches rejected by the line, word or
+// lookaround constraints are left alone.
+func (m *matcher) replace(dst, line []byte, template string) []byte {
+	var end int
+	for _, i := range m.regexp.FindAllSubmatchIndex(line, -1) {
+		if m.opts.x && (i[0] != 0 || i[1] != len(line)) || !m.accept(line, i[0], i[1]) {
+			continue
+		}
+		dst = append(dst, line[end:i[0]]...)
+		dst = m.regexp.Expand(dst, []byte(template), line, i)
+		end = i[1]
+	}
+	return append(dst, line[end:]...)
+}
+
+// preview writes line as it is and as it would be with each pattern's
+// matches replaced in turn, like a line of a unified diff. Lines without a
+// match, such as those selected by WithInvertMatch, are not written.
+func (run *run) preview(line []byte) error {
+	if len(run.matcher.indexes(line)) == 0 {
+		return nil
+	}
+	replaced := line
+	for _, m := range run.matcher.each {
+		replaced = m.replace(nil, replaced, run.cmd.opts.replaceTemplate)
+	}
+
+	run.buf = append(run.buf[:0], '-')
+	run.buf = append(run.buf, line...)
+	run.buf = run.terminate(run.buf)
+	run.buf = append(run.buf, '+')
+	run.buf = append(run.buf, replaced...)
+	run.buf = run.terminate(run.buf)
+	if _, err := run.w.Write(run.buf); err != nil {
+		return err
+	}
+	return run.lineWritten()
+}
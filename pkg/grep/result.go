@@ -0,0 +1,103 @@
+package grep
+
+import (
+	"io"
+	"strconv"
+)
+
+// Result is the outcome of a search. Its value is the exit status GNU grep
+// would report for it.
+type Result int
+
+const (
+	// Matched means at least one line was selected.
+	Matched Result = iota
+	// NoMatch means no line was selected.
+	NoMatch
+	// Error means the search failed.
+	Error
+)
+
+func (r Result) String() string {
+	switch r {
+	case Matched:
+		return "Matched"
+	case NoMatch:
+		return "NoMatch"
+	case Error:
+		return "Error"
+	}
+	return "Result(" + strconv.Itoa(int(r)) + ")"
+}
+
+// Check reports whether any line of input is selected, stopping at the first
+// one, like grep -q. WithMaxCount and WithDiffFilter apply as they do for
+// ForEach. The returned error is non-nil exactly when the Result is Error.
+func (cmd *Grep) Check(input io.Reader) (Result, error) {
+	matcher, err := cmd.allMatcher()
+	if err != nil {
+		return Error, err
+	}
+
+	if cmd.opts.m == 0 {
+		return NoMatch, nil
+	}
+
+	name := inputName(input)
+	s := matcher.newScanner(input)
+	var lineNo int
+	for s.Scan() {
+		lineNo++
+		if matcher.added != nil && !matcher.added.has(name, lineNo) {
+			continue
+		}
+		if matcher.Match(s.Bytes()) {
+			return Matched, nil
+		}
+	}
+	if err := s.Err(); err != nil {
+		return Error, err
+	}
+	return NoMatch, nil
+}
+
+// Matches reports whether any line of input is selected, stopping at the first
+// one, like grep -q. It is Check for callers that have no use for a Result.
+func (cmd *Grep) Matches(input io.Reader) (bool, error) {
+	result, err := cmd.Check(input)
+	return result == Matched, err
+}
+
+// ExecWithResult is like Read but also returns a function reporting the
+// outcome of the search, as the exit status of grep would, so callers need
+// not work it out from the output. The function blocks until the search has
+// ended, which is once the reader has been read to EOF or an error, so call
+// it after draining the reader.
+func (cmd *Grep) ExecWithResult(input io.Reader) (io.Reader, func() Result) {
+	return cmd.ReadNamedWithResult(NamedReader{Reader: input})
+}
+
+// ReadNamedWithResult is like ReadNamed but also returns a function reporting
+// the outcome of the whole search, as ExecWithResult does.
+func (cmd *Grep) ReadNamedWithResult(inputs ...NamedReader) (io.Reader, func() Result) {
+	var (
+		result Result
+		ended  = make(chan struct{})
+	)
+	r := cmd.readNamed(func(selected int, err error) {
+		switch {
+		case err != nil:
+			result = Error
+		case selected > 0:
+			result = Matched
+		default:
+			result = NoMatch
+		}
+		close(ended)
+	}, inputs...)
+
+	return r, func() Result {
+		<-ended
+		return result
+	}
+}
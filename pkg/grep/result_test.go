@@ -0,0 +1,250 @@
+package grep_test
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/kevin-cantwell/usrbin/pkg/grep"
+)
+
+// failingReader returns its data, then err.
+type failingReader struct {
+	data string
+	err  error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.data == "" {
+		return 0, r.err
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestGrep_Check(t *testing.T) {
+	errRead := errors.New("read failed")
+
+	tests := []struct {
+		name    string
+		pattern string
+		opts    []grep.Option
+		in      io.Reader
+		result  grep.Result
+		err     bool
+	}{
+		{
+			name:    "Matched",
+			pattern: "foo",
+			in:      strings.NewReader("bar\nfoo\nbaz"),
+			result:  grep.Matched,
+		},
+		{
+			name:    "Matched/early-exit",
+			pattern: "foo",
+			in:      &failingReader{data: "foo\n", err: errRead},
+			result:  grep.Matched,
+		},
+		{
+			name:    "NoMatch",
+			pattern: "foo",
+			in:      strings.NewReader("bar\nbaz"),
+			result:  grep.NoMatch,
+		},
+		{
+			name:    "NoMatch/WithInvertMatch",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithInvertMatch()},
+			in:      strings.NewReader("foo\nfoo bar"),
+			result:  grep.NoMatch,
+		},
+		{
+			name:    "NoMatch/WithMaxCount",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithMaxCount(0)},
+			in:      strings.NewReader("foo"),
+			result:  grep.NoMatch,
+		},
+		{
+			name:    "Matched/WithDiffFilter",
+			pattern: "TODO",
+			opts:    []grep.Option{grep.WithDiffFilter(strings.NewReader(sampleDiff))},
+			in:      grep.NamedReader{Name: "main.go", Reader: strings.NewReader(sampleFile)},
+			result:  grep.Matched,
+		},
+		{
+			name:    "NoMatch/WithDiffFilter",
+			pattern: "preexisting",
+			opts:    []grep.Option{grep.WithDiffFilter(strings.NewReader(sampleDiff))},
+			in:      grep.NamedReader{Name: "main.go", Reader: strings.NewReader(sampleFile)},
+			result:  grep.NoMatch,
+		},
+		{
+			name:    "Error/read",
+			pattern: "foo",
+			in:      &failingReader{data: "bar\n", err: errRead},
+			result:  grep.Error,
+			err:     true,
+		},
+		{
+			name:    "Error/pattern",
+			pattern: "[",
+			in:      strings.NewReader("foo"),
+			result:  grep.Error,
+			err:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := grep.New(tt.pattern, tt.opts...).Check(tt.in)
+			if result != tt.result {
+				t.Errorf("got %v want %v", result, tt.result)
+			}
+			if (err != nil) != tt.err {
+				t.Errorf("got err %v", err)
+			}
+		})
+	}
+}
+
+func TestGrep_Matches(t *testing.T) {
+	errRead := errors.New("read failed")
+
+	tests := []struct {
+		name    string
+		pattern string
+		in      io.Reader
+		matches bool
+		err     bool
+	}{
+		{
+			name:    "match",
+			pattern: "foo",
+			in:      strings.NewReader("bar\nfoo"),
+			matches: true,
+		},
+		{
+			name:    "early-exit",
+			pattern: "foo",
+			in:      &failingReader{data: "foo\n", err: errRead},
+			matches: true,
+		},
+		{
+			name:    "no-match",
+			pattern: "foo",
+			in:      strings.NewReader("bar"),
+		},
+		{
+			name:    "error",
+			pattern: "foo",
+			in:      &failingReader{data: "bar\n", err: errRead},
+			err:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, err := grep.New(tt.pattern).Matches(tt.in)
+			if matches != tt.matches {
+				t.Errorf("got %v want %v", matches, tt.matches)
+			}
+			if (err != nil) != tt.err {
+				t.Errorf("got err %v", err)
+			}
+		})
+	}
+}
+
+func TestResult_exitStatus(t *testing.T) {
+	for result, status := range map[grep.Result]int{grep.Matched: 0, grep.NoMatch: 1, grep.Error: 2} {
+		if int(result) != status {
+			t.Errorf("%v: got exit status %d want %d", result, int(result), status)
+		}
+	}
+}
+
+func TestGrep_ExecWithResult(t *testing.T) {
+	errRead := errors.New("read failed")
+
+	tests := []struct {
+		name    string
+		pattern string
+		opts    []grep.Option
+		in      io.Reader
+		out     string
+		result  grep.Result
+	}{
+		{
+			name:    "Matched",
+			pattern: "foo",
+			in:      strings.NewReader("bar\nfoo\nbaz foo"),
+			out:     "foo\nbaz foo\n",
+			result:  grep.Matched,
+		},
+		{
+			name:    "Matched/WithTotalOnly",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithTotalOnly()},
+			in:      strings.NewReader("foo"),
+			out:     "1\n",
+			result:  grep.Matched,
+		},
+		{
+			name:    "Matched/WithQuiet",
+			pattern: "foo",
+			opts:    []grep.Option{grep.WithQuiet(), grep.WithCount()},
+			in:      &failingReader{data: "bar\nfoo\n", err: errRead},
+			result:  grep.Matched,
+		},
+		{
+			name:    "NoMatch",
+			pattern: "foo",
+			in:      strings.NewReader("bar\nbaz"),
+			result:  grep.NoMatch,
+		},
+		{
+			name:    "Error",
+			pattern: "foo",
+			in:      &failingReader{data: "foo\n", err: errRead},
+			out:     "foo\n",
+			result:  grep.Error,
+		},
+		{
+			name:    "Error/bad-pattern",
+			pattern: "(",
+			in:      strings.NewReader("foo"),
+			result:  grep.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, result := grep.New(tt.pattern, tt.opts...).ExecWithResult(tt.in)
+			body, _ := ioutil.ReadAll(out)
+			if string(body) != tt.out {
+				t.Errorf("got %q want %q", string(body), tt.out)
+			}
+			if got := result(); got != tt.result {
+				t.Errorf("got %v want %v", got, tt.result)
+			}
+		})
+	}
+}
+
+func TestGrep_ReadNamedWithResult(t *testing.T) {
+	out, result := grep.New("foo", grep.WithLabel("in")).ReadNamedWithResult(
+		grep.NamedReader{Name: "a", Reader: strings.NewReader("bar\n")},
+		grep.NamedReader{Reader: strings.NewReader("foo\n")},
+	)
+	body, err := ioutil.ReadAll(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "in:foo\n"; string(body) != want {
+		t.Errorf("got %q want %q", string(body), want)
+	}
+	if got := result(); got != grep.Matched {
+		t.Errorf("got %v want %v", got, grep.Matched)
+	}
+}
@@ -0,0 +1,80 @@
+package grep
+
+import (
+	"io"
+	"sort"
+)
+
+// Route demultiplexes input in a single pass: each line is written to the
+// writer of every route whose pattern matches it, so a line may go to several
+// writers or none. Route patterns are matched with the options of cmd, whose
+// own patterns are ignored, as is WithDiffFilter. The route with the empty
+// pattern, if any, is the default route, which receives the lines that match
+// no other route.
+func (cmd *Grep) Route(input io.Reader, routes map[string]io.Writer) error {
+	type route struct {
+		matcher *matchAll
+		w       io.Writer
+	}
+
+	patterns := make([]string, 0, len(routes))
+	for pattern := range routes {
+		if pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	sort.Strings(patterns)
+
+	var all []route
+	for _, pattern := range patterns {
+		matcher, err := cmd.compile(pattern)
+		if err != nil {
+			return err
+		}
+		all = append(all, route{matcher, routes[pattern]})
+	}
+	fallback := routes[""]
+
+	// every route splits input into the same records
+	records, err := cmd.compile("")
+	if err != nil {
+		return err
+	}
+	terminator := byte('\n')
+	if cmd.opts.z {
+		terminator = 0
+	}
+
+	s := records.newScanner(input)
+	var buf []byte
+	for s.Scan() {
+		buf = append(buf[:0], s.Bytes()...)
+		buf = append(buf, terminator)
+
+		var routed bool
+		for _, r := range all {
+			if !r.matcher.Match(s.Bytes()) {
+				continue
+			}
+			routed = true
+			if _, err := r.w.Write(buf); err != nil {
+				return err
+			}
+		}
+		if !routed && fallback != nil {
+			if _, err := fallback.Write(buf); err != nil {
+				return err
+			}
+		}
+	}
+	return s.Err()
+}
+
+// compile returns a matcher for pattern alone, with the options of cmd but
+// none of its patterns. Diff filtering is dropped, since the diff can only be
+// read once.
+func (cmd *Grep) compile(pattern string) (*matchAll, error) {
+	opts := *cmd.opts
+	opts.e, opts.f, opts.diff = nil, nil, nil
+	return (&Grep{pattern: pattern, opts: &opts}).allMatcher()
+}
@@ -0,0 +1,79 @@
+package grep_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/kevin-cantwell/usrbin/pkg/grep"
+)
+
+func TestGrep_Route(t *testing.T) {
+	in := "INFO started\nERROR disk full\nWARN slow disk\nERROR timeout\nDEBUG tick"
+
+	tests := []struct {
+		name   string
+		opts   []grep.Option
+		routes []string
+		want   map[string]string
+	}{
+		{
+			name:   "split",
+			routes: []string{"^ERROR", "^WARN"},
+			want: map[string]string{
+				"^ERROR": "ERROR disk full\nERROR timeout\n",
+				"^WARN":  "WARN slow disk\n",
+			},
+		},
+		{
+			name:   "fan-out",
+			routes: []string{"^ERROR", "disk"},
+			want: map[string]string{
+				"^ERROR": "ERROR disk full\nERROR timeout\n",
+				"disk":   "ERROR disk full\nWARN slow disk\n",
+			},
+		},
+		{
+			name:   "default",
+			routes: []string{"^ERROR", ""},
+			want: map[string]string{
+				"^ERROR": "ERROR disk full\nERROR timeout\n",
+				"":       "INFO started\nWARN slow disk\nDEBUG tick\n",
+			},
+		},
+		{
+			name:   "WithIgnoreCase",
+			opts:   []grep.Option{grep.WithIgnoreCase()},
+			routes: []string{"error"},
+			want: map[string]string{
+				"error": "ERROR disk full\nERROR timeout\n",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sinks := map[string]*strings.Builder{}
+			routes := map[string]io.Writer{}
+			for _, pattern := range tt.routes {
+				sinks[pattern] = &strings.Builder{}
+				routes[pattern] = sinks[pattern]
+			}
+
+			if err := grep.New("", tt.opts...).Route(strings.NewReader(in), routes); err != nil {
+				t.Fatalf("got err: %#v", err)
+			}
+			for pattern, want := range tt.want {
+				if got := sinks[pattern].String(); got != want {
+					t.Errorf("%q: got %q want %q", pattern, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGrep_Route_badPattern(t *testing.T) {
+	routes := map[string]io.Writer{"(": &strings.Builder{}}
+	if err := grep.New("").Route(strings.NewReader("foo"), routes); err == nil {
+		t.Fatal("got nil error")
+	}
+}
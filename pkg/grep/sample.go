@@ -0,0 +1,75 @@
+package grep
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// sampler picks which selected lines are printed.
+type sampler struct {
+	rate float64
+	n    int
+	rand *rand.Rand
+
+	// lines offered to the reservoir so far, and those it holds
+	seen      int
+	reservoir []sampled
+}
+
+// sampled is a line held in the reservoir until every input is scanned.
+type sampled struct {
+	seq    int
+	input  NamedReader
+	lineNo int
+	offset int64
+	line   []byte
+	eol    []byte
+}
+
+func newSampler(opts *Opts) *sampler {
+	if !opts.sampled && opts.sampleN <= 0 {
+		return nil
+	}
+	src := opts.randSource
+	if src == nil {
+		src = rand.NewSource(rand.Int63())
+	}
+	rate := opts.sampleRate
+	if !opts.sampled {
+		rate = 1
+	}
+	return &sampler{rate: rate, n: opts.sampleN, rand: rand.New(src)}
+}
+
+// keep reports whether a line passes the sampling rate.
+func (s *sampler) keep() bool {
+	return s.rate >= 1 || s.rand.Float64() < s.rate
+}
+
+// add offers a line to the reservoir, which keeps each of the lines offered
+// with equal probability (Algorithm R).
+func (s *sampler) add(input NamedReader, lineNo int, offset int64, line, eol []byte) {
+	seq := s.seen
+	s.seen++
+	i := len(s.reservoir)
+	if i >= s.n {
+		if i = s.rand.Intn(s.seen); i >= s.n {
+			return
+		}
+	}
+	line = append([]byte(nil), line...)
+	eol = append([]byte(nil), eol...)
+	input.Reader = nil
+	entry := sampled{seq: seq, input: input, lineNo: lineNo, offset: offset, line: line, eol: eol}
+	if i == len(s.reservoir) {
+		s.reservoir = append(s.reservoir, entry)
+	} else {
+		s.reservoir[i] = entry
+	}
+}
+
+// lines returns the lines held in the reservoir, in input order.
+func (s *sampler) lines() []sampled {
+	sort.Slice(s.reservoir, func(i, j int) bool { return s.reservoir[i].seq < s.reservoir[j].seq })
+	return s.reservoir
+}
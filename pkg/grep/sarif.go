@@ -0,0 +1,142 @@
+package grep
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"unicode/utf8"
+)
+
+// SARIF 2.1.0, reduced to what grep reports.
+// https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool       sarifTool     `json:"tool"`
+	ColumnKind string        `json:"columnKind"`
+	Results    []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+	EndColumn   int `json:"endColumn,omitempty"`
+}
+
+func newSARIF(ms *matchAll) *sarifLog {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "grep",
+			InformationURI: "https://www.gnu.org/software/grep/manual/grep.html",
+		}},
+		ColumnKind: "unicodeCodePoints",
+		Results:    []sarifResult{},
+	}
+	for i, pattern := range ms.patterns {
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+			ID:               sarifRuleID(i),
+			ShortDescription: sarifMessage{Text: pattern},
+		})
+	}
+	return &sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	}
+}
+
+func sarifRuleID(i int) string {
+	return fmt.Sprintf("pattern-%d", i+1)
+}
+
+// add records a result for each match in line, the lineNo'th line of the
+// input named name. Lines without match positions, such as those selected by
+// WithInvertMatch, are recorded once, without columns.
+func (log *sarifLog) add(ms *matchAll, name string, lineNo int, line []byte) {
+	if name == "" {
+		name = "-"
+	}
+	uri := (&url.URL{Path: filepath.ToSlash(name)}).String()
+
+	result := func(rule int, region sarifRegion) {
+		run := &log.Runs[0]
+		run.Results = append(run.Results, sarifResult{
+			RuleID:    sarifRuleID(rule),
+			RuleIndex: rule,
+			Message:   sarifMessage{Text: string(line)},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: uri},
+				Region:           region,
+			}}},
+		})
+	}
+
+	var found bool
+	if !ms.opts.v {
+		for rule, m := range ms.each {
+			for _, i := range m.indexes(line) {
+				found = true
+				result(rule, sarifRegion{
+					StartLine:   lineNo,
+					StartColumn: utf8.RuneCount(line[:i[0]]) + 1,
+					EndColumn:   utf8.RuneCount(line[:i[1]]) + 1,
+				})
+			}
+		}
+	}
+	if !found {
+		result(0, sarifRegion{StartLine: lineNo})
+	}
+}
+
+func (log *sarifLog) write(w io.Writer) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(log)
+}
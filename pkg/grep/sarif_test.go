@@ -0,0 +1,95 @@
+package grep_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kevin-cantwell/usrbin/pkg/grep"
+)
+
+func TestWithSARIF(t *testing.T) {
+	out := grep.New("foo\nbar", grep.WithSARIF()).ReadNamed(
+		grep.NamedReader{Name: "src/a file.go", Reader: strings.NewReader("nothing\n\tfoo and bar\n")},
+		grep.NamedReader{Name: "b.go", Reader: strings.NewReader("héllo foo")},
+	)
+	body, err := ioutil.ReadAll(out)
+	if err != nil {
+		t.Fatalf("got err: %#v", err)
+	}
+
+	var log struct {
+		Version string
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name  string
+					Rules []struct {
+						ID               string
+						ShortDescription struct{ Text string }
+					}
+				}
+			}
+			Results []struct {
+				RuleID    string
+				Message   struct{ Text string }
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct{ URI string }
+						Region           struct{ StartLine, StartColumn, EndColumn int }
+					}
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal(body, &log); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, body)
+	}
+
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("got version %q with %d runs", log.Version, len(log.Runs))
+	}
+	run := log.Runs[0]
+	if run.Tool.Driver.Name != "grep" {
+		t.Errorf("got tool %q", run.Tool.Driver.Name)
+	}
+	var rules []string
+	for _, rule := range run.Tool.Driver.Rules {
+		rules = append(rules, rule.ID+"="+rule.ShortDescription.Text)
+	}
+	if want := []string{"pattern-1=foo", "pattern-2=bar"}; !reflect.DeepEqual(rules, want) {
+		t.Errorf("got rules %q want %q", rules, want)
+	}
+
+	type location struct {
+		rule, uri            string
+		line, col, endColumn int
+	}
+	var got []location
+	for _, result := range run.Results {
+		for _, loc := range result.Locations {
+			p := loc.PhysicalLocation
+			got = append(got, location{result.RuleID, p.ArtifactLocation.URI, p.Region.StartLine, p.Region.StartColumn, p.Region.EndColumn})
+		}
+	}
+	want := []location{
+		{"pattern-1", "src/a%20file.go", 2, 2, 5},
+		{"pattern-2", "src/a%20file.go", 2, 10, 13},
+		{"pattern-1", "b.go", 1, 7, 10},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got locations %+v want %+v", got, want)
+	}
+}
+
+func TestWithSARIF_noMatches(t *testing.T) {
+	body, err := ioutil.ReadAll(grep.New("foo", grep.WithSARIF()).Read(strings.NewReader("bar")))
+	if err != nil {
+		t.Fatalf("got err: %#v", err)
+	}
+	if !strings.Contains(string(body), `"results": []`) {
+		t.Errorf("expected an empty results array, got:\n%s", body)
+	}
+}
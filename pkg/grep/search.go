@@ -0,0 +1,248 @@
+package grep
+
+import (
+	"context"
+	"io"
+)
+
+// Match is a line selected by a Grep.
+type Match struct {
+	// LineNo is the 1-based number of the line in the input.
+	LineNo int
+	// ByteOffset is the offset of the line from the start of the input. It
+	// is only set by Run.
+	ByteOffset int
+	// Line is the selected line, without its terminating newline.
+	Line []byte
+	// Spans are the start and end offsets in Line of each match of the
+	// patterns, in order and with overlapping matches merged. Lines selected
+	// by WithInvertMatch have none. It is only set by Run.
+	Spans [][2]int
+	// BeforeContext holds the lines preceding Line, up to the number set by
+	// WithBeforeContext, oldest first. It is nil unless that Option is set.
+	BeforeContext []string
+	// AfterContext holds the lines following Line, up to the number set by
+	// WithAfterContext. It is nil unless that Option is set.
+	AfterContext []string
+	// PatternIndex is the index of the first pattern that matches Line, or
+	// -1 if none does, as for lines selected by WithInvertMatch. It is 0
+	// unless WithFirstMatchWins is set.
+	PatternIndex int
+}
+
+// Search is a running search started by Grep.Start.
+type Search struct {
+	results chan Match
+	cancel  context.CancelFunc
+	done    chan struct{}
+	err     error
+}
+
+// Start searches input in the background, delivering each selected line on
+// the returned Search's Results channel. It suits interactive callers, like
+// editors, that want to abandon a search as soon as it is stale. WithMaxCount
+// and WithDiffFilter apply as they do for ForEach.
+func (cmd *Grep) Start(input io.Reader) *Search {
+	return cmd.start(input, false)
+}
+
+// start is Start, also setting the ByteOffset and Spans of each Match if
+// spans is set.
+func (cmd *Grep) start(input io.Reader, spans bool) *Search {
+	ctx, cancel := context.WithCancel(context.Background())
+	search := &Search{
+		results: make(chan Match),
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+
+	go func() {
+		defer close(search.done)
+		defer close(search.results)
+		defer cancel()
+
+		matcher, err := cmd.allMatcher()
+		if err != nil {
+			search.err = err
+			return
+		}
+
+		send := func(match *Match) {
+			select {
+			case search.results <- *match:
+			case <-ctx.Done():
+			}
+		}
+		surrounding := newMatchContext(cmd.opts.B, cmd.opts.A)
+		capped := func(selected int) bool {
+			return cmd.opts.m >= 0 && selected >= cmd.opts.m
+		}
+
+		name := inputName(input)
+		s := matcher.newScanner(input)
+		var lineNo, selected int
+		for !(capped(selected) && !surrounding.waiting()) && s.Scan() {
+			lineNo++
+			if ctx.Err() != nil {
+				break
+			}
+			line := s.Bytes()
+			if matcher.added != nil && !matcher.added.has(name, lineNo) {
+				continue
+			}
+			for _, match := range surrounding.after(line) {
+				send(match)
+			}
+			if capped(selected) {
+				// only the trailing context of the last selected line remains
+				continue
+			}
+			if matcher.Match(line) {
+				selected++
+				match := &Match{LineNo: lineNo, Line: append([]byte(nil), line...)}
+				if spans {
+					match.ByteOffset = int(s.offset)
+				}
+				if spans && !cmd.opts.v {
+					for _, span := range matcher.indexes(line) {
+						match.Spans = append(match.Spans, [2]int{span[0], span[1]})
+					}
+				}
+				if cmd.opts.firstMatchWins {
+					match.PatternIndex = matcher.patternIndex(line)
+				}
+				if match := surrounding.add(match); match != nil {
+					send(match)
+				}
+			}
+			surrounding.before(line)
+		}
+		for _, match := range surrounding.flush() {
+			send(match)
+		}
+		if err := ctx.Err(); err != nil {
+			search.err = err
+			return
+		}
+		search.err = s.Err()
+	}()
+
+	return search
+}
+
+// RunResult is everything Run found in an input.
+type RunResult struct {
+	// LineCount is the number of lines selected.
+	LineCount int
+	// MatchCount is the number of matches in them, as counted by Spans.
+	MatchCount int
+	// Matches are the lines selected, in order.
+	Matches []Match
+}
+
+// Run searches input and returns every line selected, for callers that want
+// the lines and where the patterns matched in them rather than formatted
+// output. Unlike Read, it holds all of them in memory until input ends.
+func (cmd *Grep) Run(input io.Reader) (*RunResult, error) {
+	search := cmd.start(input, true)
+	result := &RunResult{}
+	for match := range search.Results() {
+		result.LineCount++
+		result.MatchCount += len(match.Spans)
+		result.Matches = append(result.Matches, match)
+	}
+	if err := search.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Results returns the channel on which selected lines are delivered. It is
+// closed when the search finishes or is cancelled.
+func (search *Search) Results() <-chan Match {
+	return search.results
+}
+
+// Err returns the error that ended the search, or nil if it ran to the end of
+// input. It returns context.Canceled if the search was cancelled. Err is only
+// meaningful once Results is closed.
+func (search *Search) Err() error {
+	select {
+	case <-search.done:
+		return search.err
+	default:
+		return nil
+	}
+}
+
+// Cancel stops the search and waits for Results to be closed. A search that is
+// blocked reading its input stops as soon as that read returns.
+func (search *Search) Cancel() {
+	search.cancel()
+	<-search.done
+}
+
+// matchContext collects the context lines of Matches, holding back each Match
+// until its trailing context is complete.
+type matchContext struct {
+	b, a int
+	// the last b lines
+	recent []string
+	// matches still gathering trailing context, oldest first
+	pending []*Match
+}
+
+func newMatchContext(b, a int) *matchContext {
+	return &matchContext{b: b, a: a}
+}
+
+// add fills in the leading context of match and returns it if it is already
+// complete.
+func (c *matchContext) add(match *Match) *Match {
+	if c.b > 0 {
+		match.BeforeContext = append([]string{}, c.recent...)
+	}
+	if c.a > 0 {
+		match.AfterContext = []string{}
+		c.pending = append(c.pending, match)
+		return nil
+	}
+	return match
+}
+
+// after adds line to the trailing context of pending matches and returns
+// those that are now complete.
+func (c *matchContext) after(line []byte) []*Match {
+	for _, match := range c.pending {
+		match.AfterContext = append(match.AfterContext, string(line))
+	}
+	var done []*Match
+	for len(c.pending) > 0 && len(c.pending[0].AfterContext) == c.a {
+		done = append(done, c.pending[0])
+		c.pending = c.pending[1:]
+	}
+	return done
+}
+
+// waiting reports whether any match is still gathering trailing context.
+func (c *matchContext) waiting() bool {
+	return len(c.pending) > 0
+}
+
+// before remembers line as leading context for the matches that follow it.
+func (c *matchContext) before(line []byte) {
+	if c.b == 0 {
+		return
+	}
+	if len(c.recent) == c.b {
+		c.recent = c.recent[1:]
+	}
+	c.recent = append(c.recent, string(line))
+}
+
+// flush returns the pending matches at the end of input.
+func (c *matchContext) flush() []*Match {
+	done := c.pending
+	c.pending = nil
+	return done
+}
@@ -0,0 +1,268 @@
+package grep_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kevin-cantwell/usrbin/pkg/grep"
+)
+
+func TestGrep_Start(t *testing.T) {
+	search := grep.New("foo").Start(strings.NewReader("foo\nbar\nbaz foo\nfoo"))
+
+	var got []grep.Match
+	for match := range search.Results() {
+		got = append(got, match)
+	}
+	if err := search.Err(); err != nil {
+		t.Fatalf("got err: %#v", err)
+	}
+
+	want := []grep.Match{
+		{LineNo: 1, Line: []byte("foo")},
+		{LineNo: 3, Line: []byte("baz foo")},
+		{LineNo: 4, Line: []byte("foo")},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q want %q", got, want)
+	}
+}
+
+// endless is an io.Reader of endless matching lines.
+type endless struct{}
+
+func (endless) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = "foo\n"[i%4]
+	}
+	return len(p) - len(p)%4, nil
+}
+
+func TestGrep_Start_cancel(t *testing.T) {
+	search := grep.New("foo").Start(endless{})
+
+	for i := 0; i < 3; i++ {
+		match := <-search.Results()
+		if match.LineNo != i+1 {
+			t.Fatalf("got line %d want %d", match.LineNo, i+1)
+		}
+	}
+
+	search.Cancel()
+
+	if _, ok := <-search.Results(); ok {
+		t.Fatal("expected results to be closed after Cancel")
+	}
+	if err := search.Err(); err != context.Canceled {
+		t.Fatalf("got err %v want %v", err, context.Canceled)
+	}
+}
+
+func TestGrep_Start_badPattern(t *testing.T) {
+	search := grep.New("[").Start(strings.NewReader("foo"))
+	for range search.Results() {
+		t.Fatal("expected no results")
+	}
+	if search.Err() == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestGrep_Start_context(t *testing.T) {
+	in := "1\n2 foo\n3\n4\n5 foo\n6 foo\n7\n8"
+
+	tests := []struct {
+		name string
+		opts []grep.Option
+		want []grep.Match
+	}{
+		{
+			name: "none",
+			want: []grep.Match{
+				{LineNo: 2, Line: []byte("2 foo")},
+				{LineNo: 5, Line: []byte("5 foo")},
+				{LineNo: 6, Line: []byte("6 foo")},
+			},
+		},
+		{
+			name: "WithBeforeContext",
+			opts: []grep.Option{grep.WithBeforeContext(2)},
+			want: []grep.Match{
+				{LineNo: 2, Line: []byte("2 foo"), BeforeContext: []string{"1"}},
+				{LineNo: 5, Line: []byte("5 foo"), BeforeContext: []string{"3", "4"}},
+				{LineNo: 6, Line: []byte("6 foo"), BeforeContext: []string{"4", "5 foo"}},
+			},
+		},
+		{
+			name: "WithAfterContext",
+			opts: []grep.Option{grep.WithAfterContext(2)},
+			want: []grep.Match{
+				{LineNo: 2, Line: []byte("2 foo"), AfterContext: []string{"3", "4"}},
+				{LineNo: 5, Line: []byte("5 foo"), AfterContext: []string{"6 foo", "7"}},
+				{LineNo: 6, Line: []byte("6 foo"), AfterContext: []string{"7", "8"}},
+			},
+		},
+		{
+			name: "WithContext/edges",
+			opts: []grep.Option{grep.WithContext(3)},
+			want: []grep.Match{
+				{LineNo: 2, Line: []byte("2 foo"), BeforeContext: []string{"1"}, AfterContext: []string{"3", "4", "5 foo"}},
+				{LineNo: 5, Line: []byte("5 foo"), BeforeContext: []string{"2 foo", "3", "4"}, AfterContext: []string{"6 foo", "7", "8"}},
+				{LineNo: 6, Line: []byte("6 foo"), BeforeContext: []string{"3", "4", "5 foo"}, AfterContext: []string{"7", "8"}},
+			},
+		},
+		{
+			name: "WithMaxCount",
+			opts: []grep.Option{grep.WithMaxCount(2), grep.WithAfterContext(1)},
+			want: []grep.Match{
+				{LineNo: 2, Line: []byte("2 foo"), AfterContext: []string{"3"}},
+				{LineNo: 5, Line: []byte("5 foo"), AfterContext: []string{"6 foo"}},
+			},
+		},
+		{
+			name: "WithMaxCount/0",
+			opts: []grep.Option{grep.WithMaxCount(0)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			search := grep.New("foo", tt.opts...).Start(strings.NewReader(in))
+
+			var got []grep.Match
+			for match := range search.Results() {
+				got = append(got, match)
+			}
+			if err := search.Err(); err != nil {
+				t.Fatalf("got err: %#v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGrep_Start_WithDiffFilter(t *testing.T) {
+	cmd := grep.New("TODO", grep.WithDiffFilter(strings.NewReader(sampleDiff)))
+	search := cmd.Start(grep.NamedReader{Name: "main.go", Reader: strings.NewReader(sampleFile)})
+
+	var got []int
+	for match := range search.Results() {
+		got = append(got, match.LineNo)
+	}
+	if err := search.Err(); err != nil {
+		t.Fatalf("got err: %#v", err)
+	}
+	if want := []int{2, 5, 12}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v want %v", got, want)
+	}
+}
+
+func TestGrep_Start_WithFirstMatchWins(t *testing.T) {
+	cmd := grep.New("", grep.WithRegexps("disk full", "disk", "full"), grep.WithFirstMatchWins())
+	search := cmd.Start(strings.NewReader("disk full\nfull disk\nfull\nnone"))
+
+	var got []int
+	for match := range search.Results() {
+		got = append(got, match.PatternIndex)
+	}
+	if err := search.Err(); err != nil {
+		t.Fatalf("got err: %#v", err)
+	}
+	if want := []int{0, 1, 2}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v want %v", got, want)
+	}
+}
+
+func TestGrep_Run(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		opts    []grep.Option
+		in      string
+		want    *grep.RunResult
+	}{
+		{
+			name:    "several matches per line",
+			pattern: "o+",
+			in:      "foo boo\nbar\nzoo\n",
+			want: &grep.RunResult{
+				LineCount:  2,
+				MatchCount: 3,
+				Matches: []grep.Match{
+					{LineNo: 1, ByteOffset: 0, Line: []byte("foo boo"), Spans: [][2]int{{1, 3}, {5, 7}}},
+					{LineNo: 3, ByteOffset: 12, Line: []byte("zoo"), Spans: [][2]int{{1, 3}}},
+				},
+			},
+		},
+		{
+			name:    "overlapping patterns",
+			pattern: "ab\nbc\nxy",
+			in:      "abc xy\n",
+			want: &grep.RunResult{
+				LineCount:  1,
+				MatchCount: 2,
+				Matches: []grep.Match{
+					{LineNo: 1, ByteOffset: 0, Line: []byte("abc xy"), Spans: [][2]int{{0, 3}, {4, 6}}},
+				},
+			},
+		},
+		{
+			name:    "WithInvertMatch",
+			pattern: "o",
+			opts:    []grep.Option{grep.WithInvertMatch()},
+			in:      "foo\nbar\n",
+			want: &grep.RunResult{
+				LineCount: 1,
+				Matches:   []grep.Match{{LineNo: 2, ByteOffset: 4, Line: []byte("bar")}},
+			},
+		},
+		{
+			name:    "WithMaxCount",
+			pattern: "o+",
+			opts:    []grep.Option{grep.WithMaxCount(1)},
+			in:      "foo\nboo\n",
+			want: &grep.RunResult{
+				LineCount:  1,
+				MatchCount: 1,
+				Matches:    []grep.Match{{LineNo: 1, ByteOffset: 0, Line: []byte("foo"), Spans: [][2]int{{1, 3}}}},
+			},
+		},
+		{
+			name:    "WithDiffFilter",
+			pattern: "o+",
+			opts:    []grep.Option{grep.WithDiffFilter(strings.NewReader("--- a/in\n+++ b/in\n@@ -1 +1,2 @@\n foo\n+boo\n"))},
+			in:      "foo\nboo\n",
+			want: &grep.RunResult{
+				LineCount:  1,
+				MatchCount: 1,
+				Matches:    []grep.Match{{LineNo: 2, ByteOffset: 4, Line: []byte("boo"), Spans: [][2]int{{1, 3}}}},
+			},
+		},
+		{
+			name:    "no match",
+			pattern: "baz",
+			in:      "foo\nbar\n",
+			want:    &grep.RunResult{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := grep.New(tt.pattern, tt.opts...).Run(grep.NamedReader{Name: "in", Reader: strings.NewReader(tt.in)})
+			if err != nil {
+				t.Fatalf("got err: %#v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGrep_Run_badPattern(t *testing.T) {
+	if _, err := grep.New("(").Run(strings.NewReader("foo\n")); err == nil {
+		t.Fatal("got no error")
+	}
+}
@@ -0,0 +1,140 @@
+package grep
+
+import (
+	"io/ioutil"
+	"os"
+)
+
+// defaultSpillThreshold is how many bytes of leading context are held in
+// memory before WithSpillContext moves them to disk.
+const defaultSpillThreshold = 1 << 20
+
+// spillFile holds the oldest lines of leading context on disk. Lines are
+// appended to the file as they spill and read back when they are printed; the
+// file is truncated once none of its lines remain in the window.
+type spillFile struct {
+	dir string
+	f   *os.File
+
+	// lines in the file still in the window, oldest first
+	lines []spilledLine
+	// offsets in the file of the first line in the window and of its end
+	start, end int64
+	// a line read back from the file
+	buf []byte
+}
+
+// spilledLine is where a line of context is in the spill file.
+type spilledLine struct {
+	lineNo int
+	offset int64
+	at     int64
+	// lengths of the line and its terminator
+	n, eol int
+}
+
+func newSpillFile(dir string) *spillFile {
+	return &spillFile{dir: dir}
+}
+
+// add appends held to the file.
+func (s *spillFile) add(held contextLine) error {
+	if s.f == nil {
+		f, err := ioutil.TempFile(s.dir, "grep-context-")
+		if err != nil {
+			return err
+		}
+		s.f = f
+	}
+	if _, err := s.f.WriteAt(held.line, s.end); err != nil {
+		return err
+	}
+	if _, err := s.f.WriteAt(held.eol, s.end+int64(len(held.line))); err != nil {
+		return err
+	}
+	s.lines = append(s.lines, spilledLine{
+		lineNo: held.lineNo,
+		offset: held.offset,
+		at:     s.end,
+		n:      len(held.line),
+		eol:    len(held.eol),
+	})
+	s.end += int64(len(held.line) + len(held.eol))
+	return nil
+}
+
+// drop removes the oldest line from the window, compacting the file when more
+// of it is out of the window than in it.
+func (s *spillFile) drop() error {
+	s.lines = s.lines[1:]
+	if len(s.lines) == 0 {
+		return s.truncate()
+	}
+	s.start = s.lines[0].at
+	if s.start < s.end-s.start {
+		return nil
+	}
+	return s.compact()
+}
+
+// compact moves the lines in the window to the start of the file.
+func (s *spillFile) compact() error {
+	buf := make([]byte, 32*1024)
+	for from, to := s.start, int64(0); from < s.end; {
+		n := int64(len(buf))
+		if n > s.end-from {
+			n = s.end - from
+		}
+		if _, err := s.f.ReadAt(buf[:n], from); err != nil {
+			return err
+		}
+		if _, err := s.f.WriteAt(buf[:n], to); err != nil {
+			return err
+		}
+		from += n
+		to += n
+	}
+	for i := range s.lines {
+		s.lines[i].at -= s.start
+	}
+	s.end -= s.start
+	s.start = 0
+	return s.f.Truncate(s.end)
+}
+
+// truncate empties the file.
+func (s *spillFile) truncate() error {
+	s.lines = s.lines[:0]
+	s.start, s.end = 0, 0
+	if s.f == nil {
+		return nil
+	}
+	return s.f.Truncate(0)
+}
+
+// read returns line l and its terminator, which are valid until the next
+// call.
+func (s *spillFile) read(l spilledLine) (line, eol []byte, err error) {
+	if n := l.n + l.eol; cap(s.buf) < n {
+		s.buf = make([]byte, n)
+	}
+	s.buf = s.buf[:l.n+l.eol]
+	if _, err := s.f.ReadAt(s.buf, l.at); err != nil {
+		return nil, nil, err
+	}
+	return s.buf[:l.n], s.buf[l.n:], nil
+}
+
+// close removes the file.
+func (s *spillFile) close() error {
+	if s.f == nil {
+		return nil
+	}
+	name := s.f.Name()
+	err := s.f.Close()
+	if rmErr := os.Remove(name); err == nil {
+		err = rmErr
+	}
+	s.f = nil
+	return err
+}
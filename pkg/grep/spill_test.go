@@ -0,0 +1,79 @@
+package grep_test
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/kevin-cantwell/usrbin/pkg/grep"
+)
+
+// dirWatcher reports whether dir had any entries while it was being read.
+type dirWatcher struct {
+	io.Reader
+	dir string
+	saw bool
+}
+
+func (r *dirWatcher) Read(p []byte) (int, error) {
+	if entries, _ := ioutil.ReadDir(r.dir); len(entries) > 0 {
+		r.saw = true
+	}
+	return r.Reader.Read(p)
+}
+
+func TestWithSpillContext(t *testing.T) {
+	in := numberedLines(5000)
+	pattern := `^(1200|1300|2999|4999)$`
+	opts := []grep.Option{grep.WithBeforeContext(1000), grep.WithAfterContext(2), grep.WithLineNumber()}
+
+	want, err := ioutil.ReadAll(grep.New(pattern, opts...).Read(strings.NewReader(in)))
+	if err != nil {
+		t.Fatalf("got err: %#v", err)
+	}
+
+	dir, err := ioutil.TempDir("", "grep-spill-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// read in small pieces, so that the spill file exists during some read
+	r := &dirWatcher{Reader: iotest.OneByteReader(strings.NewReader(in)), dir: dir}
+	opts = append(opts, grep.WithSpillContext(dir), grep.WithSpillThreshold(64))
+	got, err := ioutil.ReadAll(grep.New(pattern, opts...).Read(r))
+	if err != nil {
+		t.Fatalf("got err: %#v", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("got %d bytes of output want %d", len(got), len(want))
+	}
+	if !r.saw {
+		t.Error("context was never spilled")
+	}
+	if entries, _ := ioutil.ReadDir(dir); len(entries) > 0 {
+		t.Errorf("got %d files left in %s", len(entries), dir)
+	}
+}
+
+func TestWithSpillContext_readError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "grep-spill-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	errRead := errors.New("read failed")
+	in := &failingReader{data: numberedLines(1000), err: errRead}
+	opts := []grep.Option{grep.WithBeforeContext(500), grep.WithSpillContext(dir), grep.WithSpillThreshold(16)}
+	if _, err := ioutil.ReadAll(grep.New("^x$", opts...).Read(in)); err != errRead {
+		t.Fatalf("got err %v want %v", err, errRead)
+	}
+	if entries, _ := ioutil.ReadDir(dir); len(entries) > 0 {
+		t.Errorf("got %d files left in %s", len(entries), dir)
+	}
+}
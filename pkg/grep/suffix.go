@@ -0,0 +1,45 @@
+package grep
+
+import (
+	"regexp/syntax"
+	"unicode/utf8"
+)
+
+// reverseSyntax rewrites re in place to match the reverse of whatever it
+// matched: concatenations and literals are reversed and anchors swap ends.
+// Go regular expressions have no backreferences, so every one reverses.
+func reverseSyntax(re *syntax.Regexp) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		for i, j := 0, len(re.Rune)-1; i < j; i, j = i+1, j-1 {
+			re.Rune[i], re.Rune[j] = re.Rune[j], re.Rune[i]
+		}
+	case syntax.OpConcat:
+		for i, j := 0, len(re.Sub)-1; i < j; i, j = i+1, j-1 {
+			re.Sub[i], re.Sub[j] = re.Sub[j], re.Sub[i]
+		}
+	case syntax.OpBeginLine:
+		re.Op = syntax.OpEndLine
+	case syntax.OpEndLine:
+		re.Op = syntax.OpBeginLine
+	case syntax.OpBeginText:
+		re.Op = syntax.OpEndText
+	case syntax.OpEndText:
+		re.Op = syntax.OpBeginText
+		re.Flags &^= syntax.WasDollar
+	}
+	for _, sub := range re.Sub {
+		reverseSyntax(sub)
+	}
+}
+
+// appendReversed appends line to dst with its characters in reverse order.
+// Bytes that are not valid UTF-8 are reversed one by one.
+func appendReversed(dst, line []byte) []byte {
+	for end := len(line); end > 0; {
+		_, size := utf8.DecodeLastRune(line[:end])
+		dst = append(dst, line[end-size:end]...)
+		end -= size
+	}
+	return dst
+}
@@ -0,0 +1,38 @@
+package grep
+
+import "io"
+
+// Tally counts, in a single pass over input, the lines matching each of the
+// patterns in labeled, which maps a label such as "ERROR" to its pattern. A
+// line matching several patterns counts towards each of their labels. Every
+// label has a count, even if it is 0. Patterns are matched with the options of
+// cmd, whose own patterns are ignored.
+func (cmd *Grep) Tally(input io.Reader, labeled map[string]string) (map[string]int, error) {
+	matchers := make(map[string]*matchAll, len(labeled))
+	counts := make(map[string]int, len(labeled))
+	for label, pattern := range labeled {
+		matcher, err := cmd.compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		matchers[label] = matcher
+		counts[label] = 0
+	}
+
+	records, err := cmd.compile("")
+	if err != nil {
+		return nil, err
+	}
+	s := records.newScanner(input)
+	for s.Scan() {
+		for label, matcher := range matchers {
+			if matcher.Match(s.Bytes()) {
+				counts[label]++
+			}
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
@@ -0,0 +1,78 @@
+package grep_test
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kevin-cantwell/usrbin/pkg/grep"
+)
+
+func TestGrep_Tally(t *testing.T) {
+	log := "2019-06-01 INFO started\n" +
+		"2019-06-01 WARN disk 80% full\n" +
+		"2019-06-01 ERROR disk full\n" +
+		"2019-06-01 INFO retrying\n" +
+		"2019-06-01 ERROR gave up\n" +
+		"2019-06-01 INFO stopped"
+	severities := map[string]string{
+		"ERROR": `\bERROR\b`,
+		"WARN":  `\bWARN\b`,
+		"INFO":  `\bINFO\b`,
+		"DEBUG": `\bDEBUG\b`,
+	}
+
+	tests := []struct {
+		name    string
+		opts    []grep.Option
+		in      io.Reader
+		labeled map[string]string
+		counts  map[string]int
+		err     bool
+	}{
+		{
+			name:    "severities",
+			in:      strings.NewReader(log),
+			labeled: severities,
+			counts:  map[string]int{"ERROR": 2, "WARN": 1, "INFO": 3, "DEBUG": 0},
+		},
+		{
+			name:    "overlapping",
+			in:      strings.NewReader(log),
+			labeled: map[string]string{"ERROR": "ERROR", "disk": "disk"},
+			counts:  map[string]int{"ERROR": 2, "disk": 2},
+		},
+		{
+			name:    "WithIgnoreCase",
+			opts:    []grep.Option{grep.WithIgnoreCase()},
+			in:      strings.NewReader("error\nError\nwarn"),
+			labeled: map[string]string{"ERROR": "ERROR"},
+			counts:  map[string]int{"ERROR": 2},
+		},
+		{
+			name:    "bad-pattern",
+			in:      strings.NewReader(log),
+			labeled: map[string]string{"bad": "("},
+			err:     true,
+		},
+		{
+			name:    "read-error",
+			in:      &failingReader{data: "ERROR\n", err: errors.New("read failed")},
+			labeled: severities,
+			err:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counts, err := grep.New("", tt.opts...).Tally(tt.in, tt.labeled)
+			if !reflect.DeepEqual(counts, tt.counts) {
+				t.Errorf("got %v want %v", counts, tt.counts)
+			}
+			if (err != nil) != tt.err {
+				t.Errorf("got err %v", err)
+			}
+		})
+	}
+}
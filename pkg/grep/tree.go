@@ -0,0 +1,81 @@
+package grep
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// tree counts the lines selected in each file under root, to print them as
+// a directory tree.
+type tree struct {
+	root   string
+	counts map[string]int
+}
+
+func newTree(root string) *tree {
+	return &tree{root: root, counts: map[string]int{}}
+}
+
+func (t *tree) add(path string) {
+	t.counts[path]++
+}
+
+// write prints root and, indented beneath it, the directories and files
+// holding selected lines, with the number of lines selected in each file:
+//
+//	src/
+//	  main.go (2)
+//	  pkg/
+//	    lib.go (1)
+func (t *tree) write(w io.Writer) error {
+	if len(t.counts) == 0 {
+		return nil
+	}
+
+	type file struct {
+		path  []string
+		count int
+	}
+	var files []file
+	for path, count := range t.counts {
+		rel, err := filepath.Rel(t.root, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, file{strings.Split(filepath.ToSlash(rel), "/"), count})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		a, b := files[i].path, files[j].path
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+
+	if _, err := fmt.Fprintf(w, "%s/\n", strings.TrimSuffix(filepath.ToSlash(t.root), "/")); err != nil {
+		return err
+	}
+	var dir []string // the directory last printed, relative to root
+	for _, f := range files {
+		parent := f.path[:len(f.path)-1]
+		common := 0
+		for common < len(dir) && common < len(parent) && dir[common] == parent[common] {
+			common++
+		}
+		for depth := common; depth < len(parent); depth++ {
+			if _, err := fmt.Fprintf(w, "%s%s/\n", strings.Repeat("  ", depth+1), parent[depth]); err != nil {
+				return err
+			}
+		}
+		dir = parent
+		if _, err := fmt.Fprintf(w, "%s%s (%d)\n", strings.Repeat("  ", len(parent)+1), f.path[len(parent)], f.count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package grep_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/kevin-cantwell/usrbin/pkg/grep"
+)
+
+func TestWithTreeOutput(t *testing.T) {
+	dir := tree(t, map[string]string{
+		"main.go":             "TODO\nTODO again\n",
+		"README.md":           "nothing to do\n",
+		"cmd/tool/main.go":    "TODO\n",
+		"pkg/a/a.go":          "TODO\n",
+		"pkg/a/a_test.go":     "done\n",
+		"pkg/a/internal/x.go": "TODO\nTODO\nTODO\n",
+		"pkg/b/b.go":          "TODO\n",
+		"vendor/v.go":         "done\n",
+	})
+	defer os.RemoveAll(dir)
+
+	out := grep.New("TODO", grep.WithTreeOutput()).ReadDir(dir)
+
+	want := dir + "/\n" +
+		"  cmd/\n" +
+		"    tool/\n" +
+		"      main.go (1)\n" +
+		"  main.go (2)\n" +
+		"  pkg/\n" +
+		"    a/\n" +
+		"      a.go (1)\n" +
+		"      internal/\n" +
+		"        x.go (3)\n" +
+		"    b/\n" +
+		"      b.go (1)\n"
+	if body, err := ioutil.ReadAll(out); err != nil {
+		t.Fatalf("got err: %#v", err)
+	} else if string(body) != want {
+		t.Fatalf("got %q want %q", string(body), want)
+	}
+}
+
+func TestWithTreeOutput_noMatch(t *testing.T) {
+	dir := tree(t, map[string]string{"a.go": "done\n"})
+	defer os.RemoveAll(dir)
+
+	out := grep.New("TODO", grep.WithTreeOutput()).ReadDir(dir)
+
+	if body, err := ioutil.ReadAll(out); err != nil {
+		t.Fatalf("got err: %#v", err)
+	} else if len(body) > 0 {
+		t.Fatalf("got %q want no output", string(body))
+	}
+}
+
+func TestWithTreeOutput_Exec(t *testing.T) {
+	dir := tree(t, map[string]string{
+		"main.go":    "TODO\n",
+		"pkg/a/a.go": "TODO\nTODO\n",
+		"pkg/b/b.go": "done\n",
+	})
+	defer os.RemoveAll(dir)
+
+	out := grep.New("TODO", grep.WithTreeOutput(), grep.WithRecursive()).Exec([]string{dir, dir + "/main.go"})
+
+	want := dir + "/\n" +
+		"  main.go (1)\n" +
+		"  pkg/\n" +
+		"    a/\n" +
+		"      a.go (2)\n" +
+		dir + "/main.go:TODO\n"
+	if body, err := ioutil.ReadAll(out); err != nil {
+		t.Fatalf("got err: %#v", err)
+	} else if string(body) != want {
+		t.Fatalf("got %q want %q", string(body), want)
+	}
+}
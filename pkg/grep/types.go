@@ -0,0 +1,128 @@
+package grep
+
+import (
+	"bufio"
+	"fmt"
+	"path/filepath"
+	"sync"
+)
+
+// fileTypes maps the name of each file type to the globs matching the base
+// names of its files, like ripgrep's --type-list.
+var fileTypes = struct {
+	sync.RWMutex
+	globs map[string][]string
+}{
+	globs: map[string][]string{
+		"c":     {"*.c", "*.h"},
+		"cpp":   {"*.cc", "*.cpp", "*.cxx", "*.hh", "*.hpp", "*.hxx"},
+		"go":    {"*.go"},
+		"java":  {"*.java"},
+		"js":    {"*.js", "*.jsx", "*.mjs", "*.cjs"},
+		"json":  {"*.json"},
+		"make":  {"Makefile", "makefile", "GNUmakefile", "*.mk"},
+		"md":    {"*.md", "*.markdown"},
+		"proto": {"*.proto"},
+		"py":    {"*.py", "*.pyi"},
+		"rust":  {"*.rs"},
+		"sh":    {"*.sh", "*.bash"},
+		"ts":    {"*.ts", "*.tsx"},
+		"txt":   {"*.txt"},
+		"yaml":  {"*.yaml", "*.yml"},
+	},
+}
+
+// RegisterType adds globs to the file type name, creating it if need be, for
+// use with WithType and WithTypeNot. Globs use filepath.Match syntax and are
+// matched against base names.
+func RegisterType(name string, globs ...string) {
+	fileTypes.Lock()
+	defer fileTypes.Unlock()
+	fileTypes.globs[name] = append(fileTypes.globs[name], globs...)
+}
+
+// typeFilter selects files by type and by name, and directories by name.
+type typeFilter struct {
+	include []string
+	exclude []string
+	// globs from WithInclude, WithExclude and WithExcludeDir
+	includeNames []string
+	excludeNames []string
+	excludeDirs  []string
+}
+
+func newTypeFilter(opts *Opts) (*typeFilter, error) {
+	types, typesNot := opts.types, opts.typesNot
+	if len(types) == 0 && len(typesNot) == 0 && len(opts.include) == 0 && len(opts.exclude) == 0 &&
+		len(opts.excludeFrom) == 0 && len(opts.excludeDir) == 0 {
+		return nil, nil
+	}
+
+	f := typeFilter{
+		includeNames: opts.include,
+		excludeNames: append([]string(nil), opts.exclude...),
+		excludeDirs:  opts.excludeDir,
+	}
+	// obtain exclude patterns from files, one per line
+	for _, file := range opts.excludeFrom {
+		s := bufio.NewScanner(file)
+		for s.Scan() {
+			if glob := s.Text(); glob != "" {
+				f.excludeNames = append(f.excludeNames, glob)
+			}
+		}
+		if err := s.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	fileTypes.RLock()
+	defer fileTypes.RUnlock()
+	for _, name := range types {
+		globs, ok := fileTypes.globs[name]
+		if !ok {
+			return nil, fmt.Errorf("grep: unknown file type %q", name)
+		}
+		f.include = append(f.include, globs...)
+	}
+	for _, name := range typesNot {
+		globs, ok := fileTypes.globs[name]
+		if !ok {
+			return nil, fmt.Errorf("grep: unknown file type %q", name)
+		}
+		f.exclude = append(f.exclude, globs...)
+	}
+	return &f, nil
+}
+
+// selects reports whether the file at path is of an included type and has an
+// included name, if any were given, and is neither of an excluded type nor
+// has an excluded name.
+func (f *typeFilter) selects(path string) bool {
+	if f == nil {
+		return true
+	}
+	base := filepath.Base(path)
+	if len(f.include) > 0 && !matchAny(f.include, base) {
+		return false
+	}
+	if len(f.includeNames) > 0 && !matchAny(f.includeNames, base) {
+		return false
+	}
+	return !matchAny(f.exclude, base) && !matchAny(f.excludeNames, base)
+}
+
+// selectsDir reports whether the directory at path should be walked.
+func (f *typeFilter) selectsDir(path string) bool {
+	return f == nil || !matchAny(f.excludeDirs, filepath.Base(path))
+}
+
+// matchAny reports whether name matches any of globs.
+func matchAny(globs []string, name string) bool {
+	for _, glob := range globs {
+		if ok, _ := filepath.Match(glob, name); ok {
+			return true
+		}
+	}
+	return false
+}
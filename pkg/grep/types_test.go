@@ -0,0 +1,91 @@
+package grep_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kevin-cantwell/usrbin/pkg/grep"
+)
+
+func TestWithType(t *testing.T) {
+	grep.RegisterType("tmpl", "*.tmpl", "*.gotmpl")
+
+	dir := tree(t, map[string]string{
+		"main.go":           "TODO\n",
+		"main_test.go":      "TODO\n",
+		"pkg/lib.go":        "TODO\n",
+		"pkg/lib.py":        "TODO\n",
+		"web/app.js":        "TODO\n",
+		"README.md":         "TODO\n",
+		"Makefile":          "TODO\n",
+		"views/page.tmpl":   "TODO\n",
+		"views/mail.gotmpl": "TODO\n",
+	})
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name string
+		opts []grep.Option
+		out  []string
+	}{
+		{
+			name: "none",
+			out: []string{
+				"Makefile", "README.md", "main.go", "main_test.go", "pkg/lib.go",
+				"pkg/lib.py", "views/mail.gotmpl", "views/page.tmpl", "web/app.js",
+			},
+		},
+		{
+			name: "WithType",
+			opts: []grep.Option{grep.WithType("go")},
+			out:  []string{"main.go", "main_test.go", "pkg/lib.go"},
+		},
+		{
+			name: "WithType/several",
+			opts: []grep.Option{grep.WithType("py"), grep.WithType("js"), grep.WithType("make")},
+			out:  []string{"Makefile", "pkg/lib.py", "web/app.js"},
+		},
+		{
+			name: "WithType/RegisterType",
+			opts: []grep.Option{grep.WithType("tmpl")},
+			out:  []string{"views/mail.gotmpl", "views/page.tmpl"},
+		},
+		{
+			name: "WithTypeNot",
+			opts: []grep.Option{grep.WithTypeNot("go"), grep.WithTypeNot("tmpl")},
+			out:  []string{"Makefile", "README.md", "pkg/lib.py", "web/app.js"},
+		},
+		{
+			name: "WithType+WithTypeNot",
+			opts: []grep.Option{grep.WithType("go"), grep.WithType("md"), grep.WithTypeNot("go")},
+			out:  []string{"README.md"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var want strings.Builder
+			for _, name := range tt.out {
+				want.WriteString(filepath.Join(dir, filepath.FromSlash(name)))
+				want.WriteString(":TODO\n")
+			}
+
+			out := grep.New("TODO", tt.opts...).ReadDir(dir)
+
+			if body, err := ioutil.ReadAll(out); err != nil {
+				t.Fatalf("got err: %#v", err)
+			} else if string(body) != want.String() {
+				t.Fatalf("got %q want %q", string(body), want.String())
+			}
+		})
+	}
+}
+
+func TestWithType_unknown(t *testing.T) {
+	out := grep.New("TODO", grep.WithType("cobol")).ReadDir(".")
+	if _, err := ioutil.ReadAll(out); err == nil {
+		t.Fatal("got nil error")
+	}
+}
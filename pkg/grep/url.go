@@ -0,0 +1,49 @@
+package grep
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ExecURL fetches url and returns the lines of the response body selected by
+// cmd, searching the body as it streams in. It is ExecURLContext with the
+// background context.
+func (cmd *Grep) ExecURL(url string) (io.Reader, error) {
+	return cmd.ExecURLContext(context.Background(), url)
+}
+
+// ExecURLContext is like ExecURL but fetches url with ctx, so canceling ctx
+// or letting it time out stops the download and fails the returned reader.
+// A response with a status other than 200 OK is an error. The response body
+// is closed once the returned reader reports EOF or an error.
+func (cmd *Grep) ExecURLContext(ctx context.Context, url string) (io.Reader, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("grep: %s: %s", url, resp.Status)
+	}
+	return &closingReader{Reader: cmd.Read(resp.Body), c: resp.Body}, nil
+}
+
+// closingReader closes c once reading from it fails, as at EOF.
+type closingReader struct {
+	io.Reader
+	c io.Closer
+}
+
+func (r *closingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if err != nil {
+		r.c.Close()
+	}
+	return n, err
+}
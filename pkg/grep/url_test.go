@@ -0,0 +1,66 @@
+package grep_test
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kevin-cantwell/usrbin/pkg/grep"
+)
+
+func TestGrep_ExecURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/app.log" {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, "INFO started\nERROR disk full\nINFO tick\nERROR timeout\n")
+	}))
+	defer srv.Close()
+
+	out, err := grep.New("ERROR").ExecURL(srv.URL + "/app.log")
+	if err != nil {
+		t.Fatalf("got err: %#v", err)
+	}
+	if body, err := ioutil.ReadAll(out); err != nil {
+		t.Fatalf("got err: %#v", err)
+	} else if want := "ERROR disk full\nERROR timeout\n"; string(body) != want {
+		t.Fatalf("got %q want %q", string(body), want)
+	}
+
+	if _, err := grep.New("ERROR").ExecURL(srv.URL + "/missing.log"); err == nil {
+		t.Fatal("got nil error for 404")
+	}
+}
+
+func TestGrep_ExecURLContext_timeout(t *testing.T) {
+	unblock := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ERROR first\n")
+		w.(http.Flusher).Flush()
+		select {
+		case <-unblock:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(unblock)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	out, err := grep.New("ERROR").ExecURLContext(ctx, srv.URL)
+	if err != nil {
+		t.Fatalf("got err: %#v", err)
+	}
+	body, err := ioutil.ReadAll(out)
+	if err == nil {
+		t.Fatal("got nil error after timeout")
+	}
+	if want := "ERROR first\n"; string(body) != want {
+		t.Fatalf("got %q want %q", string(body), want)
+	}
+}
@@ -0,0 +1,45 @@
+package grep
+
+import "io"
+
+// writer is the io.WriteCloser returned by NewWriter.
+type writer struct {
+	w    *io.PipeWriter
+	done chan struct{}
+	err  error
+}
+
+// NewWriter returns a writer that searches whatever is written to it and
+// writes the selected lines to out, for producers that push data rather than
+// having it pulled, such as io.Copy from a command's output. Lines may be
+// split across any number of writes; a final line without a newline is
+// searched when the writer is closed. Close waits for all output to be
+// written to out and reports any error from the search or from out, which
+// also fails later writes.
+func (cmd *Grep) NewWriter(out io.Writer) io.WriteCloser {
+	r, w := io.Pipe()
+	wr := &writer{w: w, done: make(chan struct{})}
+
+	go func() {
+		defer close(wr.done)
+		matches := cmd.Read(r)
+		_, wr.err = io.Copy(out, matches)
+		// stop the search and fail any further writes
+		if c, ok := matches.(io.Closer); ok {
+			c.Close()
+		}
+		r.CloseWithError(wr.err)
+	}()
+
+	return wr
+}
+
+func (wr *writer) Write(p []byte) (int, error) {
+	return wr.w.Write(p)
+}
+
+func (wr *writer) Close() error {
+	wr.w.Close()
+	<-wr.done
+	return wr.err
+}
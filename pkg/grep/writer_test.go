@@ -0,0 +1,66 @@
+package grep_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kevin-cantwell/usrbin/pkg/grep"
+)
+
+func TestGrep_NewWriter(t *testing.T) {
+	in := "foo 1\nbar\nfoo 2\r\n\nbaz foo 3\nfoo 4"
+	want := "foo 1\nfoo 2\nbaz foo 3\nfoo 4\n"
+
+	for size := 1; size <= len(in); size++ {
+		t.Run(fmt.Sprint(size), func(t *testing.T) {
+			var out strings.Builder
+			w := grep.New("foo").NewWriter(&out)
+			for data := in; len(data) > 0; {
+				n := size
+				if n > len(data) {
+					n = len(data)
+				}
+				if _, err := w.Write([]byte(data[:n])); err != nil {
+					t.Fatalf("got err: %#v", err)
+				}
+				data = data[n:]
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("got err: %#v", err)
+			}
+			if out.String() != want {
+				t.Fatalf("got %q want %q", out.String(), want)
+			}
+		})
+	}
+}
+
+// failingWriter fails every write with err.
+type failingWriter struct{ err error }
+
+func (w failingWriter) Write(p []byte) (int, error) { return 0, w.err }
+
+func TestGrep_NewWriter_error(t *testing.T) {
+	t.Run("bad-pattern", func(t *testing.T) {
+		var out strings.Builder
+		w := grep.New("(").NewWriter(&out)
+		w.Write([]byte("foo\n"))
+		if err := w.Close(); err == nil {
+			t.Fatal("got nil error")
+		}
+	})
+	t.Run("out", func(t *testing.T) {
+		errWrite := errors.New("write failed")
+		w := grep.New("foo").NewWriter(failingWriter{errWrite})
+		for i := 0; i < 100; i++ {
+			if _, err := w.Write([]byte("foo\n")); err != nil {
+				break
+			}
+		}
+		if err := w.Close(); err != errWrite {
+			t.Fatalf("got err %v want %v", err, errWrite)
+		}
+	})
+}
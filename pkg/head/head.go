@@ -0,0 +1,121 @@
+/*
+	Package head exposes head-like functionality. A best effort is made to
+	mirror GNU coreutils 8.30 head (https://www.gnu.org/software/coreutils/manual/html_node/head-invocation.html).
+*/
+package head
+
+import (
+	"bufio"
+	"io"
+	"math"
+)
+
+// Option configures a Head.
+type Option func(*Opts)
+
+// WithLines outputs the first n lines. The default is 10.
+func WithLines(n int) Option {
+	return func(opts *Opts) {
+		opts.n = n
+	}
+}
+
+// WithPercent outputs the first p percent of lines, rounded to the nearest
+// line, rather than a fixed number. p is clamped to [0, 100], and NaN counts as
+// 0. Since the total number of lines must be known first, the whole input is
+// buffered in memory.
+func WithPercent(p float64) Option {
+	return func(opts *Opts) {
+		if math.IsNaN(p) {
+			p = 0
+		}
+		opts.percent = math.Max(0, math.Min(100, p))
+		opts.percentSet = true
+	}
+}
+
+type Opts struct {
+	//   -c, --bytes=[-]NUM       print the first NUM bytes of each file
+	//   -n, --lines=[-]NUM       print the first NUM lines instead of the first 10
+	n int
+	//   -q, --quiet, --silent    never print headers giving file names
+	//   -v, --verbose            always print headers giving file names
+	//   -z, --zero-terminated    line delimiter is NUL, not newline
+
+	// Extensions
+	// These have no GNU head equivalent.
+
+	// print the first percent of lines
+	percent    float64
+	percentSet bool
+}
+
+// Head outputs the first part of its input.
+type Head struct {
+	opts *Opts
+}
+
+// New returns a Head with opts set.
+func New(opts ...Option) *Head {
+	Opts := &Opts{
+		n: 10,
+	}
+	for _, opt := range opts {
+		opt(Opts)
+	}
+	return &Head{
+		opts: Opts,
+	}
+}
+
+func (cmd *Head) Read(input io.Reader) io.Reader {
+	r, w := io.Pipe()
+
+	go func() {
+		br := bufio.NewReader(input)
+
+		if cmd.opts.percentSet {
+			var lines [][]byte
+			for {
+				line, err := br.ReadBytes('\n')
+				if len(line) > 0 {
+					lines = append(lines, line)
+				}
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					w.CloseWithError(err)
+					return
+				}
+			}
+			n := int(math.Round(float64(len(lines)) * cmd.opts.percent / 100))
+			for _, line := range lines[:n] {
+				if _, err := w.Write(line); err != nil {
+					w.CloseWithError(err)
+					return
+				}
+			}
+			w.Close()
+			return
+		}
+
+		for i := 0; i < cmd.opts.n; i++ {
+			line, err := br.ReadBytes('\n')
+			if _, werr := w.Write(line); werr != nil {
+				w.CloseWithError(werr)
+				return
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				w.CloseWithError(err)
+				return
+			}
+		}
+		w.Close()
+	}()
+
+	return r
+}
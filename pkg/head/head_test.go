@@ -0,0 +1,90 @@
+package head_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/kevin-cantwell/usrbin/pkg/head"
+)
+
+// numbered returns the lines "from\n" through "to\n".
+func numbered(from, to int) string {
+	var b strings.Builder
+	for i := from; i <= to; i++ {
+		fmt.Fprintf(&b, "%d\n", i)
+	}
+	return b.String()
+}
+
+func TestHead(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []head.Option
+		in   string
+		out  string
+	}{
+		{
+			name: "default",
+			in:   numbered(1, 20),
+			out:  numbered(1, 10),
+		},
+		{
+			name: "WithLines",
+			opts: []head.Option{head.WithLines(3)},
+			in:   "a\nb\nc\nd",
+			out:  "a\nb\nc\n",
+		},
+		{
+			name: "WithLines/short",
+			opts: []head.Option{head.WithLines(3)},
+			in:   "a\nb",
+			out:  "a\nb",
+		},
+		{
+			name: "WithPercent",
+			opts: []head.Option{head.WithPercent(10)},
+			in:   numbered(1, 100),
+			out:  numbered(1, 10),
+		},
+		{
+			name: "WithPercent/round",
+			opts: []head.Option{head.WithPercent(50)},
+			in:   "a\nb\nc",
+			out:  "a\nb\n",
+		},
+		{
+			name: "WithPercent/clamp",
+			opts: []head.Option{head.WithPercent(250)},
+			in:   "a\nb\nc",
+			out:  "a\nb\nc",
+		},
+		{
+			name: "WithPercent/NaN",
+			opts: []head.Option{head.WithPercent(math.NaN())},
+			in:   "a\nb\nc",
+			out:  "",
+		},
+		{
+			name: "WithPercent/negative",
+			opts: []head.Option{head.WithPercent(-5)},
+			in:   "a\nb\nc",
+			out:  "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := strings.NewReader(tt.in)
+
+			out := head.New(tt.opts...).Read(in)
+
+			if body, err := ioutil.ReadAll(out); err != nil {
+				t.Fatalf("got err: %#v", err)
+			} else if string(body) != tt.out {
+				t.Fatalf("got %q want %q", string(body), tt.out)
+			}
+		})
+	}
+}
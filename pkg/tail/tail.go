@@ -0,0 +1,118 @@
+/*
+	Package tail exposes tail-like functionality. A best effort is made to
+	mirror GNU coreutils 8.30 tail (https://www.gnu.org/software/coreutils/manual/html_node/tail-invocation.html).
+*/
+package tail
+
+import (
+	"bufio"
+	"io"
+	"math"
+)
+
+// Option configures a Tail.
+type Option func(*Opts)
+
+// WithLines outputs the last n lines. The default is 10.
+func WithLines(n int) Option {
+	return func(opts *Opts) {
+		opts.n = n
+	}
+}
+
+// WithPercent outputs the last p percent of lines, rounded to the nearest
+// line, rather than a fixed number. p is clamped to [0, 100], and NaN counts as
+// 0. Since the total number of lines must be known first, the whole input is
+// buffered in memory.
+func WithPercent(p float64) Option {
+	return func(opts *Opts) {
+		if math.IsNaN(p) {
+			p = 0
+		}
+		opts.percent = math.Max(0, math.Min(100, p))
+		opts.percentSet = true
+	}
+}
+
+type Opts struct {
+	//   -c, --bytes=[+]NUM       output the last NUM bytes
+	//   -f, --follow[={name|descriptor}]
+	//                            output appended data as the file grows
+	//   -n, --lines=[+]NUM       output the last NUM lines, instead of the last 10
+	n int
+	//   -q, --quiet, --silent    never output headers giving file names
+	//   -s, --sleep-interval=N   with -f, sleep for approximately N seconds
+	//   -v, --verbose            always output headers giving file names
+	//   -z, --zero-terminated    line delimiter is NUL, not newline
+
+	// Extensions
+	// These have no GNU tail equivalent.
+
+	// print the last percent of lines
+	percent    float64
+	percentSet bool
+}
+
+// Tail outputs the last part of its input.
+type Tail struct {
+	opts *Opts
+}
+
+// New returns a Tail with opts set.
+func New(opts ...Option) *Tail {
+	Opts := &Opts{
+		n: 10,
+	}
+	for _, opt := range opts {
+		opt(Opts)
+	}
+	return &Tail{
+		opts: Opts,
+	}
+}
+
+func (cmd *Tail) Read(input io.Reader) io.Reader {
+	r, w := io.Pipe()
+
+	go func() {
+		br := bufio.NewReader(input)
+
+		// keep every line when the count depends on the total
+		keep := cmd.opts.n
+		if cmd.opts.percentSet {
+			keep = -1
+		}
+
+		var lines [][]byte
+		for {
+			line, err := br.ReadBytes('\n')
+			if len(line) > 0 && keep != 0 {
+				if len(lines) == keep {
+					lines = lines[1:]
+				}
+				lines = append(lines, line)
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				w.CloseWithError(err)
+				return
+			}
+		}
+
+		if cmd.opts.percentSet {
+			n := int(math.Round(float64(len(lines)) * cmd.opts.percent / 100))
+			lines = lines[len(lines)-n:]
+		}
+		for _, line := range lines {
+			if _, err := w.Write(line); err != nil {
+				w.CloseWithError(err)
+				return
+			}
+		}
+		w.Close()
+	}()
+
+	return r
+}
@@ -0,0 +1,84 @@
+package tail_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/kevin-cantwell/usrbin/pkg/tail"
+)
+
+// numbered returns the lines "from\n" through "to\n".
+func numbered(from, to int) string {
+	var b strings.Builder
+	for i := from; i <= to; i++ {
+		fmt.Fprintf(&b, "%d\n", i)
+	}
+	return b.String()
+}
+
+func TestTail(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []tail.Option
+		in   string
+		out  string
+	}{
+		{
+			name: "default",
+			in:   numbered(1, 20),
+			out:  numbered(11, 20),
+		},
+		{
+			name: "WithLines",
+			opts: []tail.Option{tail.WithLines(2)},
+			in:   "a\nb\nc\nd",
+			out:  "c\nd",
+		},
+		{
+			name: "WithLines/zero",
+			opts: []tail.Option{tail.WithLines(0)},
+			in:   "a\nb",
+			out:  "",
+		},
+		{
+			name: "WithPercent",
+			opts: []tail.Option{tail.WithPercent(10)},
+			in:   numbered(1, 100),
+			out:  numbered(91, 100),
+		},
+		{
+			name: "WithPercent/round",
+			opts: []tail.Option{tail.WithPercent(50)},
+			in:   "a\nb\nc\n",
+			out:  "b\nc\n",
+		},
+		{
+			name: "WithPercent/clamp",
+			opts: []tail.Option{tail.WithPercent(250)},
+			in:   "a\nb\nc",
+			out:  "a\nb\nc",
+		},
+		{
+			name: "WithPercent/NaN",
+			opts: []tail.Option{tail.WithPercent(math.NaN())},
+			in:   "a\nb\nc",
+			out:  "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := strings.NewReader(tt.in)
+
+			out := tail.New(tt.opts...).Read(in)
+
+			if body, err := ioutil.ReadAll(out); err != nil {
+				t.Fatalf("got err: %#v", err)
+			} else if string(body) != tt.out {
+				t.Fatalf("got %q want %q", string(body), tt.out)
+			}
+		})
+	}
+}
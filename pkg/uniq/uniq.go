@@ -0,0 +1,128 @@
+/*
+	Package uniq exposes uniq-like functionality. A best effort is made to
+	mirror GNU coreutils 8.30 uniq (https://www.gnu.org/software/coreutils/manual/html_node/uniq-invocation.html).
+*/
+package uniq
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// Option configures a Uniq.
+type Option func(*Opts)
+
+// WithAllRepeated prints every line of each run of adjacent duplicate lines
+// and nothing else, rather than one line per run. method controls how runs
+// are delimited, as GNU uniq's --all-repeated=METHOD does: "none" (or "")
+// prints them back to back, "prepend" prints a blank line before each run
+// and "separate" prints a blank line between runs. Any other method makes
+// Read fail.
+func WithAllRepeated(method string) Option {
+	return func(opts *Opts) {
+		opts.D = true
+		opts.method = method
+	}
+}
+
+type Opts struct {
+	//   -c, --count           prefix lines by the number of occurrences
+	//   -d, --repeated        only print duplicate lines, one for each group
+	//   -D                    print all duplicate lines
+	//       --all-repeated[=METHOD]  like -D, but allow separating groups
+	//                                  with an empty line;
+	//                                  METHOD={none(default),prepend,separate}
+	D      bool
+	method string
+	//   -f, --skip-fields=N   avoid comparing the first N fields
+	//       --group[=METHOD]  show all items, separating groups with an empty line;
+	//                           METHOD={separate(default),prepend,append,both}
+	//   -i, --ignore-case     ignore differences in case when comparing
+	//   -s, --skip-chars=N    avoid comparing the first N characters
+	//   -u, --unique          only print unique lines
+	//   -z, --zero-terminated     line delimiter is NUL, not newline
+	//   -w, --check-chars=N   compare no more than N characters in lines
+}
+
+// Uniq filters adjacent matching lines from its input.
+type Uniq struct {
+	opts *Opts
+}
+
+// New returns a Uniq with opts set.
+func New(opts ...Option) *Uniq {
+	Opts := &Opts{}
+	for _, opt := range opts {
+		opt(Opts)
+	}
+	return &Uniq{
+		opts: Opts,
+	}
+}
+
+func (cmd *Uniq) Read(input io.Reader) io.Reader {
+	r, w := io.Pipe()
+
+	switch cmd.opts.method {
+	case "", "none", "prepend", "separate":
+	default:
+		w.CloseWithError(fmt.Errorf("uniq: invalid argument %q for '--all-repeated'", cmd.opts.method))
+		return r
+	}
+
+	go func() {
+		br := bufio.NewReader(input)
+		bw := bufio.NewWriter(w)
+
+		var (
+			run  [][]byte // adjacent equal lines, each without its newline
+			runs int      // runs printed so far
+		)
+		flush := func() {
+			if len(run) == 0 {
+				return
+			}
+			if !cmd.opts.D {
+				bw.Write(run[0])
+				bw.WriteByte('\n')
+				return
+			}
+			if len(run) < 2 {
+				return
+			}
+			if cmd.opts.method == "prepend" || (cmd.opts.method == "separate" && runs > 0) {
+				bw.WriteByte('\n')
+			}
+			for _, line := range run {
+				bw.Write(line)
+				bw.WriteByte('\n')
+			}
+			runs++
+		}
+
+		for {
+			line, err := br.ReadBytes('\n')
+			if len(line) > 0 {
+				line = bytes.TrimSuffix(line, []byte{'\n'})
+				if len(run) > 0 && !bytes.Equal(run[0], line) {
+					flush()
+					run = run[:0]
+				}
+				run = append(run, line)
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				w.CloseWithError(err)
+				return
+			}
+		}
+		flush()
+		w.CloseWithError(bw.Flush())
+	}()
+
+	return r
+}
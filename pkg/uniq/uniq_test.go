@@ -0,0 +1,80 @@
+package uniq_test
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/kevin-cantwell/usrbin/pkg/uniq"
+)
+
+func TestUniq(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []uniq.Option
+		in   string
+		out  string
+	}{
+		{
+			name: "default",
+			in:   "a\na\nb\nc\nc\nc\na",
+			out:  "a\nb\nc\na\n",
+		},
+		{
+			name: "WithAllRepeated",
+			opts: []uniq.Option{uniq.WithAllRepeated("")},
+			in:   "a\na\nb\nc\nc\nc\nd\na\na",
+			out:  "a\na\nc\nc\nc\na\na\n",
+		},
+		{
+			name: "WithAllRepeated/none",
+			opts: []uniq.Option{uniq.WithAllRepeated("none")},
+			in:   "a\nb\nb\nc",
+			out:  "b\nb\n",
+		},
+		{
+			name: "WithAllRepeated/prepend",
+			opts: []uniq.Option{uniq.WithAllRepeated("prepend")},
+			in:   "a\na\nb\nc\nc\n",
+			out:  "\na\na\n\nc\nc\n",
+		},
+		{
+			name: "WithAllRepeated/separate",
+			opts: []uniq.Option{uniq.WithAllRepeated("separate")},
+			in:   "a\na\nb\nc\nc\nd\ne\ne\ne\n",
+			out:  "a\na\n\nc\nc\n\ne\ne\ne\n",
+		},
+		{
+			name: "WithAllRepeated/no-duplicates",
+			opts: []uniq.Option{uniq.WithAllRepeated("separate")},
+			in:   "a\nb\nc\n",
+			out:  "",
+		},
+		{
+			name: "WithAllRepeated/missing-final-newline",
+			opts: []uniq.Option{uniq.WithAllRepeated("")},
+			in:   "a\na",
+			out:  "a\na\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := strings.NewReader(tt.in)
+
+			out := uniq.New(tt.opts...).Read(in)
+
+			if body, err := ioutil.ReadAll(out); err != nil {
+				t.Fatalf("got err: %#v", err)
+			} else if string(body) != tt.out {
+				t.Fatalf("got %q want %q", string(body), tt.out)
+			}
+		})
+	}
+}
+
+func TestWithAllRepeated_invalidMethod(t *testing.T) {
+	out := uniq.New(uniq.WithAllRepeated("both")).Read(strings.NewReader("a\na\n"))
+	if _, err := ioutil.ReadAll(out); err == nil {
+		t.Fatal("got nil error")
+	}
+}
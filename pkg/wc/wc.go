@@ -0,0 +1,144 @@
+/*
+	Package wc exposes wc-like functionality. A best effort is made to
+	mirror GNU coreutils 8.30 wc (https://www.gnu.org/software/coreutils/manual/html_node/wc-invocation.html).
+*/
+package wc
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// Option configures a Wc.
+type Option func(*Opts)
+
+// WithLongestLine follows the counts with the longest line, as
+//
+//	longest:LINE:LENGTH:TEXT
+//
+// giving its 1-based line number, its length in characters and its text
+// without the newline. Of equally long lines the first is reported. Unlike
+// -L, which prints only the length, this shows which line it is, which helps
+// in tracking down malformed input. Lines are examined one at a time, so the
+// input is never held in memory.
+func WithLongestLine() Option {
+	return func(opts *Opts) {
+		opts.longest = true
+	}
+}
+
+// WithShortestLine is like WithLongestLine but reports the shortest line, as
+//
+//	shortest:LINE:LENGTH:TEXT
+//
+// Empty lines count, so this finds the first of them if there are any.
+func WithShortestLine() Option {
+	return func(opts *Opts) {
+		opts.shortest = true
+	}
+}
+
+type Opts struct {
+	//   -c, --bytes            print the byte counts
+	//   -m, --chars            print the character counts
+	//   -l, --lines            print the newline counts
+	//       --files0-from=F    read input from the files specified by
+	//                            NUL-terminated names in file F;
+	//                            If F is - then read names from standard input
+	//   -L, --max-line-length  print the maximum display width
+	//   -w, --words            print the word counts
+
+	// Extensions
+	// These have no GNU wc equivalent.
+
+	// report the text of the longest and shortest lines
+	longest  bool
+	shortest bool
+}
+
+// Wc counts the newlines, words and bytes in its input.
+type Wc struct {
+	opts *Opts
+}
+
+// New returns a Wc with opts set.
+func New(opts ...Option) *Wc {
+	Opts := &Opts{}
+	for _, opt := range opts {
+		opt(Opts)
+	}
+	return &Wc{
+		opts: Opts,
+	}
+}
+
+// extreme is the longest or shortest line seen so far.
+type extreme struct {
+	lineNo int
+	length int
+	text   []byte
+}
+
+func (e *extreme) write(w io.Writer, label string) error {
+	if e.lineNo == 0 {
+		return nil
+	}
+	_, err := fmt.Fprintf(w, "%s:%d:%d:%s\n", label, e.lineNo, e.length, e.text)
+	return err
+}
+
+func (cmd *Wc) Read(input io.Reader) io.Reader {
+	r, w := io.Pipe()
+
+	go func() {
+		br := bufio.NewReader(input)
+
+		var (
+			lines, words, bytesRead int
+			longest, shortest       extreme
+			lineNo                  int
+		)
+		for {
+			line, err := br.ReadBytes('\n')
+			if len(line) > 0 {
+				lineNo++
+				bytesRead += len(line)
+				words += len(bytes.Fields(line))
+				text := bytes.TrimSuffix(line, []byte{'\n'})
+				if len(text) < len(line) {
+					lines++
+				}
+
+				length := utf8.RuneCount(text)
+				if cmd.opts.longest && (longest.lineNo == 0 || length > longest.length) {
+					longest = extreme{lineNo: lineNo, length: length, text: text}
+				}
+				if cmd.opts.shortest && (shortest.lineNo == 0 || length < shortest.length) {
+					shortest = extreme{lineNo: lineNo, length: length, text: text}
+				}
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				w.CloseWithError(err)
+				return
+			}
+		}
+
+		if _, err := fmt.Fprintf(w, "%7d %7d %7d\n", lines, words, bytesRead); err != nil {
+			w.CloseWithError(err)
+			return
+		}
+		if err := longest.write(w, "longest"); err != nil {
+			w.CloseWithError(err)
+			return
+		}
+		w.CloseWithError(shortest.write(w, "shortest"))
+	}()
+
+	return r
+}
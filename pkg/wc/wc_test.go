@@ -0,0 +1,89 @@
+package wc_test
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/kevin-cantwell/usrbin/pkg/wc"
+)
+
+func TestWc(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []wc.Option
+		in   string
+		out  string
+	}{
+		{
+			name: "default",
+			in:   "one two\nthree\n\nfour five six\n",
+			out:  "      4       6      29\n",
+		},
+		{
+			name: "default/missing-final-newline",
+			in:   "one two\nthree",
+			out:  "      1       3      13\n",
+		},
+		{
+			name: "default/empty",
+			in:   "",
+			out:  "      0       0       0\n",
+		},
+		{
+			name: "WithLongestLine",
+			opts: []wc.Option{wc.WithLongestLine()},
+			in:   "short\na much longer line\nmid line\nanother long line!",
+			out:  "      3      10      52\nlongest:2:18:a much longer line\n",
+		},
+		{
+			name: "WithLongestLine/tie",
+			opts: []wc.Option{wc.WithLongestLine()},
+			in:   "ab\ncd\nef\n",
+			out:  "      3       3       9\nlongest:1:2:ab\n",
+		},
+		{
+			name: "WithLongestLine/characters",
+			opts: []wc.Option{wc.WithLongestLine()},
+			in:   "héllo\nworld!\n",
+			out:  "      2       2      14\nlongest:2:6:world!\n",
+		},
+		{
+			name: "WithShortestLine",
+			opts: []wc.Option{wc.WithShortestLine()},
+			in:   "three\nto\nfour\n",
+			out:  "      3       3      14\nshortest:2:2:to\n",
+		},
+		{
+			name: "WithShortestLine/empty-line",
+			opts: []wc.Option{wc.WithShortestLine()},
+			in:   "a\n\nb\n\n",
+			out:  "      4       2       6\nshortest:2:0:\n",
+		},
+		{
+			name: "WithLongestLine+WithShortestLine",
+			opts: []wc.Option{wc.WithLongestLine(), wc.WithShortestLine()},
+			in:   "bb\nccc\na\n",
+			out:  "      3       3       9\nlongest:2:3:ccc\nshortest:3:1:a\n",
+		},
+		{
+			name: "WithLongestLine/empty",
+			opts: []wc.Option{wc.WithLongestLine(), wc.WithShortestLine()},
+			in:   "",
+			out:  "      0       0       0\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := strings.NewReader(tt.in)
+
+			out := wc.New(tt.opts...).Read(in)
+
+			if body, err := ioutil.ReadAll(out); err != nil {
+				t.Fatalf("got err: %#v", err)
+			} else if string(body) != tt.out {
+				t.Fatalf("got %q want %q", string(body), tt.out)
+			}
+		})
+	}
+}
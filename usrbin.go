@@ -1,6 +1,10 @@
 package usrbin
 
-import "io"
+import (
+	"io"
+
+	"github.com/kevin-cantwell/usrbin/pkg/cat"
+)
 
 type Reader interface {
 	Read(io.Reader) io.Reader
@@ -10,6 +14,15 @@ type Execer interface {
 	Exec(params []string) io.Reader
 }
 
+// Commands maps the name of each program to a constructor for it with no
+// options set.
+var Commands = map[string]func() Execer{
+	"cat": func() Execer { return cat.New() },
+}
+
+// Pipe feeds in through each of pipes in turn and returns the output of the
+// last. Each stage reads the output of the one before it, so reading from the
+// returned reader fails with the error of any stage.
 func Pipe(in io.Reader, pipes ...Reader) io.Reader {
 	out, w := io.Pipe()
 
@@ -17,12 +30,11 @@ func Pipe(in io.Reader, pipes ...Reader) io.Reader {
 		for _, pipe := range pipes {
 			in = pipe.Read(in)
 		}
-		_, err := io.Copy(w, in)
-		if err == io.EOF {
-			w.Close()
+		if _, err := io.Copy(w, in); err != nil {
+			w.CloseWithError(err)
 			return
 		}
-		w.CloseWithError(err)
+		w.Close()
 	}()
 
 	return out
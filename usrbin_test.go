@@ -0,0 +1,121 @@
+package usrbin_test
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/kevin-cantwell/usrbin"
+	"github.com/kevin-cantwell/usrbin/pkg/grep"
+)
+
+func TestCommands(t *testing.T) {
+	dir, err := ioutil.TempDir("", "usrbin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+	if err := ioutil.WriteFile(a, []byte("foo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(b, []byte("bar\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	newCat, ok := usrbin.Commands["cat"]
+	if !ok {
+		t.Fatal("cat is not registered")
+	}
+	body, err := ioutil.ReadAll(newCat().Exec([]string{a, b}))
+	if err != nil {
+		t.Fatalf("got err: %#v", err)
+	}
+	if want := "foo\nbar\n"; string(body) != want {
+		t.Fatalf("got %q want %q", string(body), want)
+	}
+}
+
+func TestExecer_grep(t *testing.T) {
+	dir, err := ioutil.TempDir("", "usrbin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+	if err := ioutil.WriteFile(a, []byte("foo\nbar\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(b, []byte("bar\nfoo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var execer usrbin.Execer = grep.New("foo")
+	body, err := ioutil.ReadAll(execer.Exec([]string{a, b}))
+	if err != nil {
+		t.Fatalf("got err: %#v", err)
+	}
+	if want := a + ":foo\n" + b + ":foo\n"; string(body) != want {
+		t.Fatalf("got %q want %q", string(body), want)
+	}
+}
+
+// failAfter is a pipe stage that passes its input through, then fails with
+// err in place of io.EOF.
+type failAfter struct {
+	err error
+}
+
+func (f failAfter) Read(in io.Reader) io.Reader {
+	return io.MultiReader(in, failReader{f.err})
+}
+
+type failReader struct {
+	err error
+}
+
+func (f failReader) Read([]byte) (int, error) {
+	return 0, f.err
+}
+
+func TestPipe(t *testing.T) {
+	in := strings.NewReader("foo 1\nbar\nfoo 2\n")
+	body, err := ioutil.ReadAll(usrbin.Pipe(in, grep.New("foo"), grep.New("2")))
+	if err != nil {
+		t.Fatalf("got err: %#v", err)
+	}
+	if want := "foo 2\n"; string(body) != want {
+		t.Fatalf("got %q want %q", string(body), want)
+	}
+}
+
+func TestPipe_highlight(t *testing.T) {
+	in := strings.NewReader("foo 1\nbar\nfoo 2\n")
+	h := grep.NewHighlighter(regexp.MustCompile("foo"), true)
+	body, err := ioutil.ReadAll(usrbin.Pipe(in, grep.New("foo"), h))
+	if err != nil {
+		t.Fatalf("got err: %#v", err)
+	}
+	if want := "\x1b[01;31m\x1b[Kfoo\x1b[m\x1b[K 1\n\x1b[01;31m\x1b[Kfoo\x1b[m\x1b[K 2\n"; string(body) != want {
+		t.Fatalf("got %q want %q", string(body), want)
+	}
+}
+
+func TestPipe_stageError(t *testing.T) {
+	want := errors.New("stage failed")
+	in := strings.NewReader("foo 1\nbar\n")
+	body, err := ioutil.ReadAll(usrbin.Pipe(in, failAfter{want}, grep.New("foo")))
+	if err != want {
+		t.Fatalf("got err %#v want %#v", err, want)
+	}
+	if string(body) != "foo 1\n" {
+		t.Fatalf("got %q want %q", string(body), "foo 1\n")
+	}
+}